@@ -0,0 +1,20 @@
+package main
+
+import "chirpy.com/internal/apierror"
+
+// Errors returned by more than one handler
+var (
+	errUnauthorized        = apierror.Unauthorized("unauthorized", "Authentication required")
+	errInvalidCredentials  = apierror.Unauthorized("invalid_credentials", "Incorrect email or password")
+	errMissingRefreshToken = apierror.Unauthorized("missing_refresh_token", "Missing or malformed refresh token")
+	errMissingAccessToken  = apierror.Unauthorized("missing_access_token", "Missing or malformed access token")
+	errInvalidAccessToken  = apierror.Unauthorized("invalid_access_token", "Invalid or expired access token")
+	errDevOnly             = apierror.Forbidden("dev_only", "This endpoint is only available in development")
+	errChirpNotFound       = apierror.NotFound("chirp_not_found", "Chirp not found")
+	errUserNotFound        = apierror.NotFound("user_not_found", "User not found")
+	errEmailTaken          = apierror.Conflict("email_taken", "Email is already in use")
+	errHandleTaken         = apierror.Conflict("handle_taken", "Handle is already in use")
+	errAccountSuspended    = apierror.Forbidden("account_suspended", "This account has been suspended")
+	errInsufficientRole    = apierror.Forbidden("insufficient_role", "You do not have permission to do this")
+	errBlocked             = apierror.Forbidden("blocked", "You cannot interact with this user")
+)
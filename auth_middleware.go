@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const (
+	userIDContextKey contextKey = "userID"
+	roleContextKey   contextKey = "role"
+)
+
+// Rejects requests without a valid access token and stores the caller's
+// user ID and role in the request context for the wrapped handler. The
+// account is looked up too, so a suspension or a role change takes effect
+// before the caller's access token expires.
+func (cfg *apiConfig) middlewareAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			cfg.respondWithError(w, r, errMissingAccessToken)
+			return
+		}
+		_, dbUser, err := cfg.authenticate(r.Context(), token)
+		if err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+		next(w, r.WithContext(contextWithUser(r.Context(), dbUser)))
+	}
+}
+
+// Stores the caller's user ID and current role for the wrapped handler
+func contextWithUser(ctx context.Context, dbUser database.User) context.Context {
+	ctx = context.WithValue(ctx, userIDContextKey, dbUser.ID)
+	return context.WithValue(ctx, roleContextKey, auth.Role(dbUser.Role))
+}
+
+// Checks an access token and loads the account it was issued to. Tokens
+// of deleted accounts are invalid and suspended accounts are refused, so
+// both take effect before the token expires. The claims are returned
+// whenever the token itself is valid.
+func (cfg *apiConfig) authenticate(ctx context.Context, token string) (auth.Claims, database.User, error) {
+	claims, err := auth.ParseJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return auth.Claims{}, database.User{}, errInvalidAccessToken
+	}
+	dbUser, err := cfg.queries.GetUserByID(ctx, claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return claims, database.User{}, errInvalidAccessToken
+	}
+	if err != nil {
+		return claims, database.User{}, apierror.Internal("user_fetch_failed", "Failed to fetch user", err)
+	}
+	setRequestUser(ctx, dbUser.ID)
+	if dbUser.SuspendedAt.Valid {
+		return claims, database.User{}, errAccountSuspended
+	}
+	return claims, dbUser, nil
+}
+
+// Like middlewareAuth but lets anonymous requests through. A valid access
+// token still identifies the caller, e.g. to personalize responses. A
+// request whose token middlewareAuth would refuse, including one for a
+// deleted or suspended account, is served as anonymous.
+func (cfg *apiConfig) middlewareOptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			next(w, r)
+			return
+		}
+		_, dbUser, err := cfg.authenticate(r.Context(), token)
+		if err != nil {
+			var apiErr *apierror.Error
+			if errors.As(err, &apiErr) && apiErr.Kind == apierror.KindInternal {
+				log.Printf("Error authenticating optional caller: %s", err)
+			}
+			next(w, r)
+			return
+		}
+		next(w, r.WithContext(contextWithUser(r.Context(), dbUser)))
+	}
+}
+
+// Like middlewareAuth but also requires the caller to hold at least min.
+// The role checked is the one currently stored for the caller, not the one
+// in their token.
+func (cfg *apiConfig) middlewareRole(min auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.middlewareAuth(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := roleFromContext(r.Context()); !role.AtLeast(min) {
+			cfg.respondWithError(w, r, errInsufficientRole)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func userIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDContextKey).(uuid.UUID)
+	return userID, ok
+}
+
+func roleFromContext(ctx context.Context) (auth.Role, bool) {
+	role, ok := ctx.Value(roleContextKey).(auth.Role)
+	return role, ok
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestMiddlewareOptionalAuthServesBadTokensAnonymously(t *testing.T) {
+	cfg, fake := newTestConfig(t)
+	active := fake.addUser(auth.RoleUser, false)
+	suspended := fake.addUser(auth.RoleUser, true)
+	deleted := database.User{ID: uuid.New(), Role: string(auth.RoleUser)}
+	expired, err := auth.MakeJWT(uuid.New(), auth.RoleUser, cfg.jwtSecret, -time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to make token: %v", err)
+	}
+	foreign, err := auth.MakeJWT(uuid.New(), auth.RoleUser, "other-secret", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to make token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantUser      bool
+	}{
+		{name: "no token", authorization: ""},
+		{name: "expired token", authorization: "Bearer " + expired},
+		{name: "token signed with another secret", authorization: "Bearer " + foreign},
+		{name: "malformed token", authorization: "Bearer not-a-jwt"},
+		{name: "suspended user", authorization: bearer(t, cfg, suspended)},
+		{name: "deleted user", authorization: bearer(t, cfg, deleted)},
+		{name: "active user", authorization: bearer(t, cfg, active), wantUser: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := cfg.middlewareOptionalAuth(func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := userIDFromContext(r.Context()); ok != tt.wantUser {
+					t.Errorf("request has a user = %v, want %v", ok, tt.wantUser)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAuthRejectsSuspendedUsers(t *testing.T) {
+	cfg, fake := newTestConfig(t)
+	active := fake.addUser(auth.RoleUser, false)
+	suspended := fake.addUser(auth.RoleUser, true)
+
+	tests := []struct {
+		name        string
+		user        database.User
+		wantReached bool
+	}{
+		{name: "active user", user: active, wantReached: true},
+		{name: "suspended user", user: suspended, wantReached: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			handler := cfg.middlewareAuth(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/timeline", nil)
+			req.Header.Set("Authorization", bearer(t, cfg, tt.user))
+			handler(httptest.NewRecorder(), req)
+			if reached != tt.wantReached {
+				t.Errorf("handler reached = %v, want %v", reached, tt.wantReached)
+			}
+		})
+	}
+}
+
+func TestSuspendedUserCannotPost(t *testing.T) {
+	cfg, fake := newTestConfig(t)
+	suspended := fake.addUser(auth.RoleUser, true)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(`{"body":"still here"}`))
+	req.Header.Set("Authorization", bearer(t, cfg, suspended))
+	rec := httptest.NewRecorder()
+	cfg.middlewareAuth(cfg.chirpsHandler)(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if !strings.Contains(rec.Body.String(), `"account_suspended"`) {
+		t.Errorf("body = %s, want code account_suspended", rec.Body.String())
+	}
+	if fake.didRun("CreateChirp") {
+		t.Error("CreateChirp ran for a suspended user")
+	}
+}
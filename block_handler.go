@@ -0,0 +1,211 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// An entry in the caller's block or mute list
+type RelationEntry struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Handle    string    `json:"handle,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Blocks {userID} and removes any follows between the two users. Blocking
+// someone who is already blocked is not an error.
+func (cfg *apiConfig) blockHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	blocked, err := cfg.pathUser(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if blocked.ID == userID {
+		cfg.respondWithError(w, r, apierror.Validation("cannot_block_self", "You cannot block yourself"))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("block_failed", "Failed to block user", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	_, err = qtx.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userID,
+		BlockedID: blocked.ID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("block_failed", "Failed to block user", err))
+		return
+	}
+	err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+		FollowerID: userID,
+		FolloweeID: blocked.ID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("block_failed", "Failed to block user", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("block_failed", "Failed to block user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unblocking someone who isn't blocked is not an error. Follows removed by
+// the block are not restored.
+func (cfg *apiConfig) unblockHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	_, err = cfg.queries.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("unblock_failed", "Failed to unblock user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Hides {userID}'s chirps and notifications from the caller without them
+// knowing. Muting someone who is already muted is not an error.
+func (cfg *apiConfig) muteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	muted, err := cfg.pathUser(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if muted.ID == userID {
+		cfg.respondWithError(w, r, apierror.Validation("cannot_mute_self", "You cannot mute yourself"))
+		return
+	}
+	_, err = cfg.queries.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: muted.ID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("mute_failed", "Failed to mute user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unmuting someone who isn't muted is not an error
+func (cfg *apiConfig) unmuteHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	_, err = cfg.queries.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("unmute_failed", "Failed to unmute user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Fetches one page of the caller's block or mute list
+type relationPageFunc func(r *http.Request, userID uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, limit int32) ([]RelationEntry, error)
+
+// Lists the users the caller has blocked, most recent first
+func (cfg *apiConfig) listBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listRelations(w, r, func(r *http.Request, userID uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, limit int32) ([]RelationEntry, error) {
+		rows, err := cfg.queries.ListBlocks(r.Context(), database.ListBlocksParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit,
+		})
+		entries := make([]RelationEntry, len(rows))
+		for i, row := range rows {
+			entries[i] = RelationEntry{UserID: row.ID, Handle: row.Handle.String, CreatedAt: row.CreatedAt}
+		}
+		return entries, err
+	})
+}
+
+// Lists the users the caller has muted, most recent first
+func (cfg *apiConfig) listMutesHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listRelations(w, r, func(r *http.Request, userID uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, limit int32) ([]RelationEntry, error) {
+		rows, err := cfg.queries.ListMutes(r.Context(), database.ListMutesParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit,
+		})
+		entries := make([]RelationEntry, len(rows))
+		for i, row := range rows {
+			entries[i] = RelationEntry{UserID: row.ID, Handle: row.Handle.String, CreatedAt: row.CreatedAt}
+		}
+		return entries, err
+	})
+}
+
+// Shared pagination for block and mute lists
+func (cfg *apiConfig) listRelations(w http.ResponseWriter, r *http.Request, fetch relationPageFunc) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	entries, err := fetch(r, userID, cursorCreatedAt, cursorID, int32(limit+1))
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("relations_fetch_failed", "Failed to fetch users", err))
+		return
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.UserID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+	cfg.respondWithJSON(w, http.StatusOK, entries)
+}
@@ -1,21 +1,119 @@
 package main
 
 import (
+	"context"
 	"time"
 
+	"chirpy.com/internal/database"
 	"github.com/google/uuid"
 )
 
 // Used to hold a chirp json response
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Body         string     `json:"body"`
+	UserID       uuid.UUID  `json:"user_id"`
+	InReplyToID  *uuid.UUID `json:"in_reply_to_id,omitempty"`
+	ThreadRootID uuid.UUID  `json:"thread_root_id"`
+	LikeCount    int        `json:"like_count"`
+	LikedByMe    bool       `json:"liked_by_me"`
+	RechirpCount int        `json:"rechirp_count"`
+	QuoteCount   int        `json:"quote_count"`
+	// The original of a pure rechirp or of a quote chirp
+	RechirpOf *EmbeddedChirp `json:"rechirp_of,omitempty"`
+	QuoteOf   *EmbeddedChirp `json:"quote_of,omitempty"`
+	Entities  ChirpEntities  `json:"entities"`
+	// Set on tombstones standing in for deleted chirps inside a thread
+	Deleted bool `json:"deleted,omitempty"`
 }
 
-type CreateChirpRequest struct {
-	Body   string    `json:"body"`
+// A chirp shown inside another one. Only the ID is kept once the original
+// has been deleted.
+type EmbeddedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Body      string     `json:"body,omitempty"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+}
+
+// Hashtags and mentions found in a chirp body. Offsets count runes; start
+// is the position of the '#' or '@' and end is exclusive.
+type ChirpEntities struct {
+	Hashtags []HashtagEntity `json:"hashtags"`
+	Mentions []MentionEntity `json:"mentions"`
+}
+
+type HashtagEntity struct {
+	Tag   string `json:"tag"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Only mentions of existing handles are kept
+type MentionEntity struct {
 	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+}
+
+// Maps a SQLC chirp to the Chirp json response
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:           dbChirp.ID,
+		CreatedAt:    dbChirp.CreatedAt,
+		UpdatedAt:    dbChirp.UpdatedAt,
+		Body:         dbChirp.Body,
+		UserID:       dbChirp.UserID.UUID,
+		ThreadRootID: dbChirp.ThreadRootID,
+		Entities: ChirpEntities{
+			Hashtags: []HashtagEntity{},
+			Mentions: []MentionEntity{},
+		},
+	}
+	if dbChirp.InReplyToID.Valid {
+		chirp.InReplyToID = &dbChirp.InReplyToID.UUID
+	}
+	// Only the IDs are known here, hydrateChirps fills in the originals
+	if dbChirp.RechirpOfID.Valid {
+		chirp.RechirpOf = &EmbeddedChirp{ID: dbChirp.RechirpOfID.UUID}
+	}
+	if dbChirp.QuoteOfID.Valid {
+		chirp.QuoteOf = &EmbeddedChirp{ID: dbChirp.QuoteOfID.UUID}
+	}
+	return chirp
+}
+
+func chirpsFromDB(dbChirps []database.Chirp) []Chirp {
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+	return chirps
+}
+
+// Fills in the fields of chirp responses that come from other tables:
+// likes, rechirp counts, embedded originals and entities. Each is loaded with one
+// query for the whole batch.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, chirps []*Chirp) error {
+	if err := cfg.attachLikes(ctx, chirps); err != nil {
+		return err
+	}
+	if err := cfg.attachRechirpCounts(ctx, chirps); err != nil {
+		return err
+	}
+	if err := cfg.attachEmbeddedChirps(ctx, chirps); err != nil {
+		return err
+	}
+	return cfg.attachEntities(ctx, chirps)
+}
+
+// The author is taken from the access token, never from the request body
+type CreateChirpRequest struct {
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
 }
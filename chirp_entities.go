@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+
+	"chirpy.com/internal/database"
+	"chirpy.com/internal/entities"
+	"github.com/google/uuid"
+)
+
+// Parses the hashtags and mentions out of a new chirp and stores them,
+// returning the IDs of the mentioned users. Mentions of handles nobody has
+// registered are dropped.
+func storeChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) ([]uuid.UUID, error) {
+	found := entities.Parse(chirp.Body)
+	var mentioned []uuid.UUID
+
+	if len(found.Hashtags) > 0 {
+		// A tag used twice in one chirp must only be upserted once
+		var names []string
+		seen := map[string]bool{}
+		for _, tag := range found.Hashtags {
+			if !seen[tag.Tag] {
+				seen[tag.Tag] = true
+				names = append(names, tag.Tag)
+			}
+		}
+		hashtags, err := q.UpsertHashtags(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		hashtagIDs := make(map[string]uuid.UUID, len(hashtags))
+		for _, hashtag := range hashtags {
+			hashtagIDs[hashtag.Name] = hashtag.ID
+		}
+		for _, tag := range found.Hashtags {
+			err := q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+				ChirpID:     chirp.ID,
+				HashtagID:   hashtagIDs[tag.Tag],
+				StartOffset: int32(tag.Start),
+				EndOffset:   int32(tag.End),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(found.Mentions) > 0 {
+		handles := make([]string, len(found.Mentions))
+		for i, mention := range found.Mentions {
+			handles[i] = entities.NormalizeHandle(mention.Handle)
+		}
+		users, err := q.GetUsersByHandles(ctx, handles)
+		if err != nil {
+			return nil, err
+		}
+		userIDs := make(map[string]uuid.UUID, len(users))
+		for _, user := range users {
+			userIDs[entities.NormalizeHandle(user.Handle.String)] = user.ID
+		}
+		for _, mention := range found.Mentions {
+			userID, ok := userIDs[entities.NormalizeHandle(mention.Handle)]
+			if !ok {
+				continue
+			}
+			mentioned = append(mentioned, userID)
+			err := q.CreateMention(ctx, database.CreateMentionParams{
+				ChirpID:     chirp.ID,
+				UserID:      userID,
+				StartOffset: int32(mention.Start),
+				EndOffset:   int32(mention.End),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return mentioned, nil
+}
+
+func (cfg *apiConfig) attachEntities(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	byID := make(map[uuid.UUID]*Chirp, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+		byID[chirp.ID] = chirp
+	}
+
+	hashtags, err := cfg.queries.GetHashtagEntities(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range hashtags {
+		chirp := byID[row.ChirpID]
+		chirp.Entities.Hashtags = append(chirp.Entities.Hashtags, HashtagEntity{
+			Tag:   row.Name,
+			Start: int(row.StartOffset),
+			End:   int(row.EndOffset),
+		})
+	}
+
+	mentions, err := cfg.queries.GetMentionEntities(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range mentions {
+		chirp := byID[row.ChirpID]
+		chirp.Entities.Mentions = append(chirp.Entities.Mentions, MentionEntity{
+			UserID: row.UserID,
+			Handle: row.Handle.String,
+			Start:  int(row.StartOffset),
+			End:    int(row.EndOffset),
+		})
+	}
+	return nil
+}
@@ -1,57 +1,181 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
+	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
+	"chirpy.com/internal/apierror"
 	"chirpy.com/internal/database"
 	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) chirpsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	decoder := json.NewDecoder(r.Body)
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
 	params := CreateChirpRequest{}
-	err := decoder.Decode(&params)
-	// Respond with Error if problems marshalling JSON
+	err := decodeJSONBody(r, &params)
+	// Respond with Error if problems unmarshalling JSON
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	// Respond with Error if the chirp is too long or breaks content rules
+	moderated, err := moderateBody(cfg.moderator, "chirp", maxChirpLength, params.Body)
 	if err != nil {
-		msg := fmt.Sprintf("Error marshalling JSON: %s", err)
-		cfg.respondWithError(w, 500, msg)
+		cfg.respondWithError(w, r, err)
 		return
 	}
-	// Respond with Error if message is > 140 characters
-	if len(params.Body) > 140 {
-		statusCode := 400
-		msg := "Chirp is too long"
-		cfg.respondWithError(w, statusCode, msg)
+	// A reply joins its parent's thread, anything else starts a new one
+	chirpID := uuid.New()
+	threadRootID := chirpID
+	inReplyTo := uuid.NullUUID{}
+	parentAuthor := uuid.NullUUID{}
+	// Authors of the chirps this one replies to or quotes
+	var referencedAuthors []uuid.UUID
+	if params.InReplyTo != nil {
+		parent, err := cfg.referencedChirp(r, *params.InReplyTo, "in_reply_to")
+		if err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+		threadRootID = parent.ThreadRootID
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		parentAuthor = parent.UserID
+		if parent.UserID.Valid {
+			referencedAuthors = append(referencedAuthors, parent.UserID.UUID)
+		}
+	}
+	quoteOf := uuid.NullUUID{}
+	if params.QuoteOf != nil {
+		quoted, err := cfg.referencedChirp(r, *params.QuoteOf, "quote_of")
+		if err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+		quoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+		if quoted.UserID.Valid {
+			referencedAuthors = append(referencedAuthors, quoted.UserID.UUID)
+		}
+	}
+	if err := cfg.ensureNotBlocked(r.Context(), userID, referencedAuthors...); err != nil {
+		cfg.respondWithError(w, r, err)
 		return
 	}
-	// Use removeProfanity to clean the Chirp Body
-	cleanedBody := removeProfanity(params.Body)
-	// Chirp is valid if past this point
-	chirp, err := cfg.queries.CreateChirp(r.Context(), database.CreateChirpParams{
-		ID:        uuid.New(),
+	// Chirp is valid if past this point. It is stored together with its
+	// hashtags and mentions so feeds never see a half-written chirp.
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_create_failed", "Failed to create chirp", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:        chirpID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		Body:      cleanedBody,
+		Body:      moderated.Text,
 		UserID: uuid.NullUUID{
-			UUID:  params.UserID,
+			UUID:  userID,
 			Valid: true,
 		},
+		InReplyToID:  inReplyTo,
+		ThreadRootID: threadRootID,
+		QuoteOfID:    quoteOf,
 	})
 	if err != nil {
-		cfg.respondWithError(w, 500, "Failed to create chirp")
+		cfg.respondWithError(w, r, apierror.Internal("chirp_create_failed", "Failed to create chirp", err))
 		return
 	}
-	chirpResponse := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID.UUID,
+	mentioned, err := storeChirpEntities(r.Context(), qtx, chirp)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_entities_failed", "Failed to store hashtags and mentions", err))
+		return
+	}
+	// Flagged chirps are published but queued for a moderator
+	if moderated.Flagged {
+		err := qtx.CreateFlagReport(r.Context(), database.CreateFlagReportParams{
+			ChirpID: chirp.ID,
+			Reason:  reportReasonFlagged,
+			Details: strings.Join(moderated.Reasons, ", "),
+		})
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("chirp_create_failed", "Failed to create chirp", err))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_create_failed", "Failed to create chirp", err))
+		return
 	}
+	cfg.metrics.chirpsCreated.Inc()
+	chirpResponse := chirpFromDB(chirp)
+	if err := cfg.hydrateChirps(r.Context(), []*Chirp{&chirpResponse}); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
+	}
+	cfg.publishChirp(chirpResponse)
+	cfg.notifyChirpCreated(chirp, parentAuthor, mentioned)
 	cfg.respondWithJSON(w, 201, chirpResponse)
 	return
 }
+
+// Looks up a chirp referenced from a request body field. Pure rechirps
+// resolve to their original so references always point at real content.
+func (cfg *apiConfig) referencedChirp(r *http.Request, id uuid.UUID, field string) (database.Chirp, error) {
+	chirp, err := cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerFromContext(r.Context()),
+	})
+	if err == nil && chirp.RechirpOfID.Valid {
+		chirp, err = cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       chirp.RechirpOfID.UUID,
+			ViewerID: viewerFromContext(r.Context()),
+		})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Chirp{}, apierror.Validation("referenced_chirp_not_found", "The referenced chirp does not exist", apierror.FieldError{
+			Field:   field,
+			Code:    "not_found",
+			Message: "must reference an existing chirp",
+		})
+	}
+	if err != nil {
+		return database.Chirp{}, apierror.Internal("chirp_fetch_failed", "Chirp unable to be fetched", err)
+	}
+	return chirp, nil
+}
+
+// Tells the author of the parent chirp about a reply and mentioned users
+// about the mention. Someone mentioned in a reply to their own chirp only
+// hears about the reply.
+func (cfg *apiConfig) notifyChirpCreated(chirp database.Chirp, parentAuthor uuid.NullUUID, mentioned []uuid.UUID) {
+	chirpID := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	if parentAuthor.Valid {
+		cfg.notifier.enqueue(notificationJob{
+			Kind:       notificationReply,
+			ActorID:    chirp.UserID.UUID,
+			ChirpID:    chirpID,
+			Recipients: []uuid.UUID{parentAuthor.UUID},
+		})
+	}
+	var mentionRecipients []uuid.UUID
+	for _, userID := range mentioned {
+		if !parentAuthor.Valid || userID != parentAuthor.UUID {
+			mentionRecipients = append(mentionRecipients, userID)
+		}
+	}
+	cfg.notifier.enqueue(notificationJob{
+		Kind:       notificationMention,
+		ActorID:    chirp.UserID.UUID,
+		ChirpID:    chirpID,
+		Recipients: mentionRecipients,
+	})
+}
@@ -0,0 +1,61 @@
+// Command polkasim is a local stand-in for the Polka payment provider. It
+// posts a signed webhook event to a running Chirpy server so the
+// /api/polka/webhooks endpoint can be exercised without the real provider.
+//
+// Usage:
+//
+//	POLKA_WEBHOOK_SECRET=... go run ./cmd/polkasim -user <uuid>
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"chirpy.com/internal/auth"
+	"github.com/google/uuid"
+)
+
+func main() {
+	target := flag.String("url", "http://localhost:8080/api/polka/webhooks", "webhook endpoint")
+	userID := flag.String("user", "", "ID of the user the event refers to")
+	event := flag.String("event", "user.upgraded", "event type to send")
+	eventID := flag.String("id", uuid.NewString(), "event ID, reuse it to test idempotency")
+	apiKey := flag.Bool("apikey", false, "authenticate with POLKA_KEY instead of a signature")
+	flag.Parse()
+
+	if *userID == "" {
+		log.Fatal("-user is required")
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"id":    *eventID,
+		"event": *event,
+		"data":  map[string]string{"user_id": *userID},
+	})
+	if err != nil {
+		log.Fatalf("Error marshalling event: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, *target, bytes.NewReader(payload))
+	if err != nil {
+		log.Fatalf("Error building request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if *apiKey {
+		req.Header.Set("Authorization", "ApiKey "+os.Getenv("POLKA_KEY"))
+	} else {
+		req.Header.Set("X-Polka-Signature", auth.SignPayload(payload, os.Getenv("POLKA_WEBHOOK_SECRET")))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("Error sending event: %v", err)
+	}
+	defer resp.Body.Close()
+	fmt.Printf("event %s (%s) -> %s\n", *eventID, *event, resp.Status)
+}
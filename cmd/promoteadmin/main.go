@@ -0,0 +1,91 @@
+// Command promoteadmin makes an existing user the first admin, so that
+// someone can reach the role-guarded /admin routes and hand out roles from
+// there. It refuses to run once an admin exists unless -force is given.
+//
+// Usage:
+//
+//	go run ./cmd/promoteadmin -email admin@example.com
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user to promote")
+	force := flag.Bool("force", false, "promote even if an admin already exists")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("-email is required")
+	}
+	// The environment may be set without a .env file
+	_ = godotenv.Load()
+	dbURL := os.Getenv("DB_URL")
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("Connection to database failed with error: %v. Check your DB_URL (%s)", err, dbURL)
+	}
+	defer db.Close()
+	queries := database.New(db)
+	ctx := context.Background()
+
+	admins, err := queries.CountUsersWithRole(ctx, string(auth.RoleAdmin))
+	if err != nil {
+		log.Fatalf("Error counting admins: %v", err)
+	}
+	if admins > 0 && !*force {
+		log.Fatalf("%d admin(s) already exist, promote further users through PUT /admin/users/{userID}/role or pass -force", admins)
+	}
+	user, err := queries.GetUserByEmail(ctx, *email)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Fatalf("No user with email %s", *email)
+	}
+	if err != nil {
+		log.Fatalf("Error fetching user: %v", err)
+	}
+
+	details, err := json.Marshal(map[string]any{"role": auth.RoleAdmin, "via": "promoteadmin"})
+	if err != nil {
+		log.Fatalf("Error encoding audit details: %v", err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatalf("Error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+	_, err = qtx.SetUserRole(ctx, database.SetUserRoleParams{
+		ID:   user.ID,
+		Role: string(auth.RoleAdmin),
+	})
+	if err != nil {
+		log.Fatalf("Error promoting user: %v", err)
+	}
+	// Run from a shell, so there is no actor to record
+	err = qtx.CreateAuditEntry(ctx, database.CreateAuditEntryParams{
+		Action:     "user.set_role",
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Details:    details,
+	})
+	if err != nil {
+		log.Fatalf("Error recording audit entry: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error committing: %v", err)
+	}
+	fmt.Printf("%s (%s) is now an admin\n", user.Email, user.ID)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// Largest number of people in a conversation, including whoever started it
+const maxConversationMembers = 10
+
+var errConversationNotFound = apierror.NotFound("conversation_not_found", "Conversation not found")
+
+type Conversation struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// One-to-one conversations are reused rather than started again
+	IsDirect    bool                 `json:"is_direct"`
+	Members     []ConversationMember `json:"members"`
+	UnreadCount int                  `json:"unread_count"`
+}
+
+type ConversationMember struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+// Keys a one-to-one conversation by its two members regardless of who
+// started it
+func directKey(a, b uuid.UUID) string {
+	ids := []string{a.String(), b.String()}
+	sort.Strings(ids)
+	return strings.Join(ids, ":")
+}
+
+// Starts a conversation between the caller and member_ids. Asking for a
+// one-to-one conversation that already exists returns it with 200.
+func (cfg *apiConfig) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MemberIDs []uuid.UUID `json:"member_ids"`
+	}
+
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+
+	members := []uuid.UUID{userID}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range params.MemberIDs {
+		if !seen[id] {
+			seen[id] = true
+			members = append(members, id)
+		}
+	}
+	if len(members) < 2 {
+		cfg.respondWithError(w, r, invalidMemberIDs("required", "must include at least one other user"))
+		return
+	}
+	if len(members) > maxConversationMembers {
+		cfg.respondWithError(w, r, invalidMemberIDs("too_many", fmt.Sprintf("must include at most %d other users", maxConversationMembers-1)))
+		return
+	}
+	existing, err := cfg.queries.GetUserIDs(r.Context(), members)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversation_create_failed", "Failed to create conversation", err))
+		return
+	}
+	if len(existing) != len(members) {
+		cfg.respondWithError(w, r, invalidMemberIDs("not_found", "must reference existing users"))
+		return
+	}
+	if err := cfg.ensureNotBlocked(r.Context(), userID, members[1:]...); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+
+	key := sql.NullString{}
+	if len(members) == 2 {
+		key = sql.NullString{String: directKey(members[0], members[1]), Valid: true}
+		conversation, err := cfg.queries.GetConversationByDirectKey(r.Context(), key)
+		if err == nil {
+			cfg.respondWithConversation(w, r, http.StatusOK, conversation)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			cfg.respondWithError(w, r, apierror.Internal("conversation_create_failed", "Failed to create conversation", err))
+			return
+		}
+	}
+
+	conversation, err := cfg.createConversation(r.Context(), userID, key, members)
+	// Lost a race with the other member starting the same conversation
+	if key.Valid && isUniqueViolationOn(err, conversationsDirectKeyKey) {
+		conversation, err = cfg.queries.GetConversationByDirectKey(r.Context(), key)
+		if err == nil {
+			cfg.respondWithConversation(w, r, http.StatusOK, conversation)
+			return
+		}
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversation_create_failed", "Failed to create conversation", err))
+		return
+	}
+	cfg.respondWithConversation(w, r, http.StatusCreated, conversation)
+}
+
+const conversationsDirectKeyKey = "conversations_direct_key_key"
+
+func invalidMemberIDs(code, message string) error {
+	return apierror.Validation("invalid_members", "Invalid conversation members", apierror.FieldError{
+		Field:   "member_ids",
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Stores a conversation together with its members
+func (cfg *apiConfig) createConversation(ctx context.Context, createdBy uuid.UUID, key sql.NullString, members []uuid.UUID) (database.Conversation, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	conversation, err := qtx.CreateConversation(ctx, database.CreateConversationParams{
+		CreatedBy: uuid.NullUUID{UUID: createdBy, Valid: true},
+		DirectKey: key,
+	})
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	err = qtx.AddConversationMembers(ctx, database.AddConversationMembersParams{
+		ConversationID: conversation.ID,
+		UserIds:        members,
+	})
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	return conversation, tx.Commit()
+}
+
+func (cfg *apiConfig) respondWithConversation(w http.ResponseWriter, r *http.Request, code int, dbConversation database.Conversation) {
+	conversation := Conversation{
+		ID:        dbConversation.ID,
+		CreatedAt: dbConversation.CreatedAt,
+		UpdatedAt: dbConversation.UpdatedAt,
+		IsDirect:  dbConversation.DirectKey.Valid,
+	}
+	if err := cfg.attachMembers(r.Context(), []*Conversation{&conversation}); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversation_fetch_failed", "Failed to fetch conversation", err))
+		return
+	}
+	cfg.respondWithJSON(w, code, conversation)
+}
+
+// Lists the caller's conversations, most recently active first. Paginated
+// with ?limit= and ?cursor= like GET /api/chirps.
+func (cfg *apiConfig) listConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorUpdatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	rows, err := cfg.queries.ListConversations(r.Context(), database.ListConversationsParams{
+		UserID:          userID,
+		CursorUpdatedAt: cursorUpdatedAt,
+		CursorID:        cursorID,
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversations_fetch_failed", "Failed to fetch conversations", err))
+		return
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.UpdatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+
+	conversations := make([]Conversation, len(rows))
+	for i, row := range rows {
+		conversations[i] = Conversation{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			IsDirect:    row.DirectKey.Valid,
+			UnreadCount: int(row.UnreadCount),
+		}
+	}
+	ptrs := make([]*Conversation, len(conversations))
+	for i := range conversations {
+		ptrs[i] = &conversations[i]
+	}
+	if err := cfg.attachMembers(r.Context(), ptrs); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversations_fetch_failed", "Failed to fetch conversations", err))
+		return
+	}
+	cfg.respondWithJSON(w, http.StatusOK, conversations)
+}
+
+// Fills in Members for every conversation with a single query
+func (cfg *apiConfig) attachMembers(ctx context.Context, conversations []*Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+	rows, err := cfg.queries.GetConversationMembers(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byConversation := make(map[uuid.UUID][]ConversationMember, len(conversations))
+	for _, row := range rows {
+		member := ConversationMember{UserID: row.UserID}
+		if row.LastReadAt.Valid {
+			member.LastReadAt = &row.LastReadAt.Time
+		}
+		byConversation[row.ConversationID] = append(byConversation[row.ConversationID], member)
+	}
+	for _, conversation := range conversations {
+		conversation.Members = byConversation[conversation.ID]
+		if conversation.Members == nil {
+			conversation.Members = []ConversationMember{}
+		}
+	}
+	return nil
+}
+
+// Resolves the {conversationID} path value to a conversation the caller is
+// a member of. Conversations the caller isn't in are reported as missing.
+func (cfg *apiConfig) pathConversation(r *http.Request, userID uuid.UUID) (database.Conversation, error) {
+	id, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		return database.Conversation{}, errConversationNotFound
+	}
+	conversation, err := cfg.queries.GetConversationForMember(r.Context(), database.GetConversationForMemberParams{
+		ConversationID: id,
+		UserID:         userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Conversation{}, errConversationNotFound
+	}
+	if err != nil {
+		return database.Conversation{}, apierror.Internal("conversation_fetch_failed", "Failed to fetch conversation", err)
+	}
+	return conversation, nil
+}
+
+// Marks the conversation read for the caller up to and including
+// up_to_id, or up to now when it is omitted. Read state never moves
+// backwards.
+func (cfg *apiConfig) readConversationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		UpToID *uuid.UUID `json:"up_to_id"`
+	}
+
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	conversation, err := cfg.pathConversation(r, userID)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	// An empty body marks everything read
+	params := parameters{}
+	if r.ContentLength != 0 {
+		if err := decodeJSONBody(r, &params); err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+	}
+
+	arg := database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	}
+	if params.UpToID != nil {
+		upTo, err := cfg.queries.GetMessage(r.Context(), database.GetMessageParams{
+			ID:             *params.UpToID,
+			ConversationID: conversation.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			cfg.respondWithError(w, r, apierror.Validation("message_not_found", "The message does not exist", apierror.FieldError{
+				Field:   "up_to_id",
+				Code:    "not_found",
+				Message: "must reference a message in this conversation",
+			}))
+			return
+		}
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("messages_fetch_failed", "Failed to fetch messages", err))
+			return
+		}
+		arg.ReadAt = sql.NullTime{Time: upTo.CreatedAt, Valid: true}
+	}
+	if err := cfg.queries.MarkConversationRead(r.Context(), arg); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversation_update_failed", "Failed to mark conversation read", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const pqUniqueViolation = "23505"
+
+// Reports whether err is a Postgres UNIQUE constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
+
+// Reports whether err is a UNIQUE violation of the named constraint or
+// index
+func isUniqueViolationOn(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation && pqErr.Constraint == constraint
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// Soft-deletes a chirp so it can later be restored by a moderator
+func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	dbChirp, err := cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_fetch_failed", "Chirp unable to be fetched", err))
+		return
+	}
+	if dbChirp.UserID.UUID != userID {
+		cfg.respondWithError(w, r, apierror.Forbidden("not_chirp_owner", "You can only delete your own chirps"))
+		return
+	}
+	if err := cfg.queries.SoftDeleteChirp(r.Context(), id); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_delete_failed", "Chirp unable to be deleted", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// An in-memory stand-in for Postgres. It answers the sqlc queries the
+// handler tests need, recognised by their "-- name:" comment, and records
+// every statement it is asked to run.
+type fakeDB struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]database.User
+	ran   []string
+	// Name of a statement that fails when it is run
+	failing string
+}
+
+// Returns a config whose queries run against a fresh fakeDB
+func newTestConfig(t *testing.T) (*apiConfig, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{users: make(map[uuid.UUID]database.User)}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	cfg := &apiConfig{
+		db:        db,
+		queries:   database.New(db),
+		jwtSecret: "test-secret",
+	}
+	return cfg, fake
+}
+
+func (f *fakeDB) addUser(role auth.Role, suspended bool) database.User {
+	now := time.Now().UTC()
+	user := database.User{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Email:     uuid.NewString() + "@example.com",
+		Role:      string(role),
+	}
+	if suspended {
+		user.SuspendedAt = sql.NullTime{Time: now, Valid: true}
+	}
+	f.mu.Lock()
+	f.users[user.ID] = user
+	f.mu.Unlock()
+	return user
+}
+
+// Reports whether a query with the given sqlc name was run
+func (f *fakeDB) didRun(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ran := range f.ran {
+		if ran == name {
+			return true
+		}
+	}
+	return false
+}
+
+func bearer(t *testing.T, cfg *apiConfig, user database.User) string {
+	t.Helper()
+	token, err := auth.MakeJWT(user.ID, auth.Role(user.Role), cfg.jwtSecret, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to make token: %v", err)
+	}
+	return "Bearer " + token
+}
+
+var queryName = regexp.MustCompile(`-- name: (\w+)`)
+
+func (f *fakeDB) query(query string, args []driver.Value) (driver.Rows, error) {
+	name := queryName.FindStringSubmatch(query)[1]
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ran = append(f.ran, name)
+
+	switch name {
+	case "GetUserByID":
+		id, err := uuid.Parse(fmt.Sprint(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		rows := &fakeRows{columns: []string{"id", "created_at", "updated_at", "email", "hashed_password", "is_chirpy_red", "handle", "suspended_at", "role"}}
+		if u, ok := f.users[id]; ok {
+			var suspendedAt driver.Value
+			if u.SuspendedAt.Valid {
+				suspendedAt = u.SuspendedAt.Time
+			}
+			rows.values = append(rows.values, []driver.Value{
+				u.ID.String(), u.CreatedAt, u.UpdatedAt, u.Email, u.HashedPassword, u.IsChirpyRed, nil, suspendedAt, u.Role,
+			})
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("fakedb: unexpected query %s", name)
+}
+
+func (f *fakeDB) exec(query string) (driver.Result, error) {
+	name := queryName.FindStringSubmatch(query)[1]
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ran = append(f.ran, name)
+	if name == f.failing {
+		return nil, fmt.Errorf("fakedb: %s failed", name)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return fakeDriver{f}
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.db.exec(s.query)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.query(s.query, args)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
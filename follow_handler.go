@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// An entry in a follower or following list
+type FollowEntry struct {
+	UserID      uuid.UUID `json:"user_id"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	FollowedAt  time.Time `json:"followed_at"`
+}
+
+// Resolves the {userID} path value to an existing user
+func (cfg *apiConfig) pathUser(r *http.Request) (database.User, error) {
+	id, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		return database.User{}, errUserNotFound
+	}
+	user, err := cfg.queries.GetUserByID(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errUserNotFound
+	}
+	if err != nil {
+		return database.User{}, apierror.Internal("user_fetch_failed", "Failed to fetch user", err)
+	}
+	return user, nil
+}
+
+// Following someone who is already followed is not an error
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	followee, err := cfg.pathUser(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if followee.ID == userID {
+		cfg.respondWithError(w, r, apierror.Validation("cannot_follow_self", "You cannot follow yourself"))
+		return
+	}
+	if err := cfg.ensureNotBlocked(r.Context(), userID, followee.ID); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	followed, err := cfg.queries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("follow_failed", "Failed to follow user", err))
+		return
+	}
+	if followed > 0 {
+		cfg.notifier.enqueue(notificationJob{
+			Kind:       notificationFollow,
+			ActorID:    userID,
+			Recipients: []uuid.UUID{followee.ID},
+		})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unfollowing someone who isn't followed is not an error
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	_, err = cfg.queries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("unfollow_failed", "Failed to unfollow user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Fetches one page of a follow list for the given user
+type followPageFunc func(r *http.Request, userID uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, limit int32) ([]FollowEntry, error)
+
+// Lists the users following {userID}, most recent first
+func (cfg *apiConfig) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(r *http.Request, userID uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, limit int32) ([]FollowEntry, error) {
+		rows, err := cfg.queries.ListFollowers(r.Context(), database.ListFollowersParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit,
+		})
+		entries := make([]FollowEntry, len(rows))
+		for i, row := range rows {
+			entries[i] = FollowEntry{UserID: row.ID, IsChirpyRed: row.IsChirpyRed, FollowedAt: row.FollowedAt}
+		}
+		return entries, err
+	})
+}
+
+// Lists the users {userID} follows, most recent first
+func (cfg *apiConfig) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(r *http.Request, userID uuid.UUID, cursorCreatedAt sql.NullTime, cursorID uuid.NullUUID, limit int32) ([]FollowEntry, error) {
+		rows, err := cfg.queries.ListFollowing(r.Context(), database.ListFollowingParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit,
+		})
+		entries := make([]FollowEntry, len(rows))
+		for i, row := range rows {
+			entries[i] = FollowEntry{UserID: row.ID, IsChirpyRed: row.IsChirpyRed, FollowedAt: row.FollowedAt}
+		}
+		return entries, err
+	})
+}
+
+// Shared pagination for both directions of the follow graph
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, fetch followPageFunc) {
+	user, err := cfg.pathUser(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	entries, err := fetch(r, user.ID, cursorCreatedAt, cursorID, int32(limit+1))
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("follows_fetch_failed", "Failed to fetch follows", err))
+		return
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.FollowedAt, ID: last.UserID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+	cfg.respondWithJSON(w, http.StatusOK, entries)
+}
@@ -1,35 +1,85 @@
 package main
 
 import (
-	"encoding/json"
 	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
 )
 
+// Lists chirps one page at a time. Supports ?author_id=, ?sort=asc|desc,
+// ?limit= and ?cursor=. When more chirps are available the URL of the next
+// page is returned in the Link header.
 func (cfg *apiConfig) getAllChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	// Querying the database for all Chirps
-	dbChirps, err := cfg.queries.GetAllChirps(r.Context())
+	query := r.URL.Query()
+
+	authorID := uuid.NullUUID{}
+	if raw := query.Get("author_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			cfg.respondWithError(w, r, invalidQueryParam("author_id", "must be a valid UUID"))
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	sortOrder := query.Get("sort")
+	if sortOrder == "" {
+		sortOrder = "asc"
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		cfg.respondWithError(w, r, invalidQueryParam("sort", "must be asc or desc"))
+		return
+	}
+
+	limit, err := parseLimit(query)
 	if err != nil {
-		cfg.respondWithError(w, http.StatusInternalServerError, "Failed to fetch chirps")
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
 		return
 	}
-	// Explicitly map SQLC chirps to custom Chirp Struct
-	chirps := make([]Chirp, len(dbChirps))
-	for i, dbChirp := range dbChirps {
-		chirps[i] = Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID.UUID,
-		}
+
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	var dbChirps []database.Chirp
+	if sortOrder == "desc" {
+		dbChirps, err = cfg.queries.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        viewerFromContext(r.Context()),
+			Limit:           int32(limit + 1),
+		})
+	} else {
+		dbChirps, err = cfg.queries.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        viewerFromContext(r.Context()),
+			Limit:           int32(limit + 1),
+		})
 	}
-	// Set headers for JSON response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	// Encoding chirps to JSON and writes to w
-	err = json.NewEncoder(w).Encode(chirps)
 	if err != nil {
-		cfg.respondWithError(w, http.StatusInternalServerError, "Failed to encode chirps")
+		cfg.respondWithError(w, r, apierror.Internal("chirps_fetch_failed", "Failed to fetch chirps", err))
+		return
+	}
+
+	if len(dbChirps) > limit {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+
+	chirps := chirpsFromDB(dbChirps)
+	if err := cfg.hydrateChirps(r.Context(), chirpPointers(chirps)); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
 		return
 	}
+	cfg.respondWithJSON(w, http.StatusOK, chirps)
 }
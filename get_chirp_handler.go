@@ -1,8 +1,12 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -12,23 +16,26 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse string into a unique user id
 	id, err := uuid.Parse(chirpID)
 	if err != nil {
-		cfg.respondWithError(w, http.StatusNotFound, "chirp not found")
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	dbChirp, err := cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, errChirpNotFound)
 		return
 	}
-	dbChirp, err := cfg.queries.GetChirp(r.Context(), id)
 	if err != nil {
-		cfg.respondWithError(w, http.StatusInternalServerError, "chirp unable to be fetched")
+		cfg.respondWithError(w, r, apierror.Internal("chirp_fetch_failed", "Chirp unable to be fetched", err))
 		return
 	}
-	// Map DB Query to a Go Struct
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID.UUID,
+	chirp := chirpFromDB(dbChirp)
+	if err := cfg.hydrateChirps(r.Context(), []*Chirp{&chirp}); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
 	}
 	// MarshalChirp to JSON response and send out!
-
 	cfg.respondWithJSON(w, http.StatusOK, chirp)
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// A chirp and the replies to it, recursively
+type ThreadNode struct {
+	Chirp
+	Depth      int          `json:"depth"`
+	ReplyCount int          `json:"reply_count"`
+	Replies    []ThreadNode `json:"replies"`
+}
+
+// Returns the whole conversation {chirpID} belongs to as a tree rooted at
+// the first chirp of the thread. Deleted chirps, chirps withheld by a
+// moderator and chirps by authors hidden from the caller are kept as
+// tombstones so their replies stay attached.
+func (cfg *apiConfig) getThreadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	dbChirp, err := cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_fetch_failed", "Chirp unable to be fetched", err))
+		return
+	}
+
+	rows, err := cfg.queries.GetThread(r.Context(), database.GetThreadParams{
+		ViewerID: viewerFromContext(r.Context()),
+		RootID:   dbChirp.ThreadRootID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("thread_fetch_failed", "Thread unable to be fetched", err))
+		return
+	}
+	if len(rows) == 0 {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	thread := buildThread(rows)
+	if err := cfg.hydrateChirps(r.Context(), thread.chirps()); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
+	}
+	cfg.respondWithJSON(w, http.StatusOK, thread)
+}
+
+// Returns every chirp in the tree, for decorating them in one batch.
+// Tombstones are left out so hydration can't fill their details back in.
+func (n *ThreadNode) chirps() []*Chirp {
+	chirps := []*Chirp{}
+	if !n.Deleted {
+		chirps = append(chirps, &n.Chirp)
+	}
+	for i := range n.Replies {
+		chirps = append(chirps, n.Replies[i].chirps()...)
+	}
+	return chirps
+}
+
+// Assembles the flat rows returned by GetThread into a tree. Rows arrive
+// ordered by depth so every parent is seen before its replies.
+func buildThread(rows []database.GetThreadRow) ThreadNode {
+	children := make(map[uuid.UUID][]database.GetThreadRow)
+	for _, row := range rows[1:] {
+		children[row.InReplyToID.UUID] = append(children[row.InReplyToID.UUID], row)
+	}
+
+	var build func(row database.GetThreadRow) ThreadNode
+	build = func(row database.GetThreadRow) ThreadNode {
+		node := ThreadNode{
+			Chirp: chirpFromDB(database.Chirp{
+				ID:           row.ID,
+				CreatedAt:    row.CreatedAt,
+				UpdatedAt:    row.UpdatedAt,
+				Body:         row.Body,
+				UserID:       row.UserID,
+				DeletedAt:    row.DeletedAt,
+				InReplyToID:  row.InReplyToID,
+				ThreadRootID: row.ThreadRootID,
+				RechirpOfID:  row.RechirpOfID,
+				QuoteOfID:    row.QuoteOfID,
+			}),
+			Depth:   int(row.Depth),
+			Replies: []ThreadNode{},
+		}
+		if row.DeletedAt.Valid || row.Withheld {
+			node.Body = ""
+			node.UserID = uuid.Nil
+			node.RechirpOf = nil
+			node.QuoteOf = nil
+			node.Entities = ChirpEntities{Hashtags: []HashtagEntity{}, Mentions: []MentionEntity{}}
+			node.Deleted = true
+		}
+		for _, child := range children[row.ID] {
+			reply := build(child)
+			if !reply.Deleted {
+				node.ReplyCount++
+			}
+			node.Replies = append(node.Replies, reply)
+		}
+		return node
+	}
+	return build(rows[0])
+}
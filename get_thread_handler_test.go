@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestThreadChirpsSkipsTombstones(t *testing.T) {
+	now := time.Now()
+	root := uuid.New()
+	row := func(id uuid.UUID, parent uuid.UUID, depth int32) database.GetThreadRow {
+		r := database.GetThreadRow{
+			ID:           id,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			Body:         "hello #go",
+			UserID:       uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			ThreadRootID: root,
+			Depth:        depth,
+		}
+		if parent != uuid.Nil {
+			r.InReplyToID = uuid.NullUUID{UUID: parent, Valid: true}
+		}
+		return r
+	}
+	deleted, withheld, visible := uuid.New(), uuid.New(), uuid.New()
+	rows := []database.GetThreadRow{
+		row(root, uuid.Nil, 0),
+		row(deleted, root, 1),
+		row(withheld, root, 1),
+		row(visible, deleted, 2),
+	}
+	rows[1].DeletedAt = sql.NullTime{Time: now, Valid: true}
+	rows[2].Withheld = true
+
+	thread := buildThread(rows)
+	got := map[uuid.UUID]bool{}
+	for _, c := range thread.chirps() {
+		got[c.ID] = true
+	}
+	if !got[root] || !got[visible] {
+		t.Errorf("chirps() = %v, want the root and the visible reply", got)
+	}
+	if got[deleted] || got[withheld] {
+		t.Errorf("chirps() = %v, want tombstones left out", got)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"chirpy.com/internal/entities"
+)
+
+// Lists chirps tagged with {tag}, newest first. The tag may be given with
+// or without its '#' and in any case. Paginated with ?limit= and ?cursor=
+// like GET /api/chirps.
+func (cfg *apiConfig) hashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeTag(r.PathValue("tag"))
+	if !entities.ValidTag(tag) {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_hashtag", "Invalid hashtag", apierror.FieldError{
+			Field:   "tag",
+			Code:    "invalid",
+			Message: "must contain only letters, digits or underscores and not only digits",
+		}))
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	dbChirps, err := cfg.queries.ListChirpsByHashtag(r.Context(), database.ListChirpsByHashtagParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		ViewerID:        viewerFromContext(r.Context()),
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_fetch_failed", "Failed to fetch chirps", err))
+		return
+	}
+	if len(dbChirps) > limit {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+	chirps := chirpsFromDB(dbChirps)
+	if err := cfg.hydrateChirps(r.Context(), chirpPointers(chirps)); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
+	}
+	cfg.respondWithJSON(w, http.StatusOK, chirps)
+}
@@ -0,0 +1,169 @@
+// Package apierror maps domain errors to HTTP statuses and renders them as
+// RFC 7807 application/problem+json documents.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem type URIs are built from this base and the error Kind
+const TypeBaseURI = "https://chirpy.com/problems/"
+
+// Broad category of an error. Each kind has a stable problem type URI and
+// maps to exactly one HTTP status.
+type Kind string
+
+const (
+	KindMalformed    Kind = "malformed-request"
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindNotFound     Kind = "not-found"
+	KindConflict     Kind = "conflict"
+	KindRateLimited  Kind = "rate-limited"
+	KindInternal     Kind = "internal"
+)
+
+var kindStatus = map[Kind]int{
+	KindMalformed:    http.StatusBadRequest,
+	KindValidation:   http.StatusBadRequest,
+	KindUnauthorized: http.StatusUnauthorized,
+	KindForbidden:    http.StatusForbidden,
+	KindNotFound:     http.StatusNotFound,
+	KindConflict:     http.StatusConflict,
+	KindRateLimited:  http.StatusTooManyRequests,
+	KindInternal:     http.StatusInternalServerError,
+}
+
+var kindTitle = map[Kind]string{
+	KindMalformed:    "Malformed request",
+	KindValidation:   "Validation failed",
+	KindUnauthorized: "Unauthorized",
+	KindForbidden:    "Forbidden",
+	KindNotFound:     "Not found",
+	KindConflict:     "Conflict",
+	KindRateLimited:  "Too many requests",
+	KindInternal:     "Internal server error",
+}
+
+// Describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Kind Kind
+	// Stable machine readable identifier, e.g. "chirp_not_found"
+	Code   string
+	Detail string
+	Fields []FieldError
+	// Underlying cause, never shown to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Detail + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Status() int {
+	if status, ok := kindStatus[e.Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func New(kind Kind, code, detail string) *Error {
+	return &Error{Kind: kind, Code: code, Detail: detail}
+}
+
+// The request body could not be parsed at all
+func Malformed(err error) *Error {
+	return &Error{Kind: KindMalformed, Code: "malformed_json", Detail: "Request body is not valid JSON", Err: err}
+}
+
+func Validation(code, detail string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Detail: detail, Fields: fields}
+}
+
+func Unauthorized(code, detail string) *Error {
+	return New(KindUnauthorized, code, detail)
+}
+
+func Forbidden(code, detail string) *Error {
+	return New(KindForbidden, code, detail)
+}
+
+func NotFound(code, detail string) *Error {
+	return New(KindNotFound, code, detail)
+}
+
+func Conflict(code, detail string) *Error {
+	return New(KindConflict, code, detail)
+}
+
+func RateLimited(code, detail string) *Error {
+	return New(KindRateLimited, code, detail)
+}
+
+func Internal(code, detail string, err error) *Error {
+	return &Error{Kind: KindInternal, Code: code, Detail: detail, Err: err}
+}
+
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Builds the problem document for err. Errors that are not an *Error are
+// reported as a generic internal error so their text never leaks.
+func ProblemFor(err error, instance string) Problem {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = Internal("internal_error", "An unexpected error occurred", err)
+	}
+	kind := apiErr.Kind
+	if _, ok := kindStatus[kind]; !ok {
+		kind = KindInternal
+	}
+	return Problem{
+		Type:     TypeBaseURI + string(kind),
+		Title:    kindTitle[kind],
+		Status:   apiErr.Status(),
+		Detail:   apiErr.Detail,
+		Instance: instance,
+		Code:     apiErr.Code,
+		Errors:   apiErr.Fields,
+	}
+}
+
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	problem := ProblemFor(err, r.URL.Path)
+	data, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		log.Printf("Error marshalling problem: %s", marshalErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(data)
+}
@@ -0,0 +1,101 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *Error
+		want int
+	}{
+		{name: "malformed", err: Malformed(errors.New("unexpected EOF")), want: http.StatusBadRequest},
+		{name: "validation", err: Validation("chirp_too_long", "Chirp is too long"), want: http.StatusBadRequest},
+		{name: "unauthorized", err: Unauthorized("invalid_token", "Invalid access token"), want: http.StatusUnauthorized},
+		{name: "forbidden", err: Forbidden("not_chirp_owner", "Not your chirp"), want: http.StatusForbidden},
+		{name: "not found", err: NotFound("chirp_not_found", "Chirp not found"), want: http.StatusNotFound},
+		{name: "conflict", err: Conflict("email_taken", "Email is already in use"), want: http.StatusConflict},
+		{name: "rate limited", err: RateLimited("login_locked", "Too many attempts"), want: http.StatusTooManyRequests},
+		{name: "internal", err: Internal("db_error", "Failed", errors.New("boom")), want: http.StatusInternalServerError},
+		{name: "unknown kind", err: New("bogus", "code", "detail"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Status(); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProblemForWrappedError(t *testing.T) {
+	field := FieldError{Field: "body", Code: "too_long", Message: "must be at most 140 characters"}
+	err := fmt.Errorf("creating chirp: %w", Validation("chirp_too_long", "Chirp is too long", field))
+
+	got := ProblemFor(err, "/api/chirps")
+	want := Problem{
+		Type:     TypeBaseURI + "validation",
+		Title:    "Validation failed",
+		Status:   http.StatusBadRequest,
+		Detail:   "Chirp is too long",
+		Instance: "/api/chirps",
+		Code:     "chirp_too_long",
+		Errors:   []FieldError{field},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestProblemForHidesUnknownErrors(t *testing.T) {
+	got := ProblemFor(errors.New("pq: password authentication failed"), "/api/users")
+	if got.Status != http.StatusInternalServerError {
+		t.Errorf("got status %d, want 500", got.Status)
+	}
+	if got.Code != "internal_error" {
+		t.Errorf("got code %q, want internal_error", got.Code)
+	}
+	if got.Detail != "An unexpected error occurred" {
+		t.Errorf("Expected generic detail, got %q", got.Detail)
+	}
+}
+
+func TestInternalErrorUnwraps(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Internal("chirp_fetch_failed", "Failed to fetch chirp", cause)
+	if !errors.Is(err, cause) {
+		t.Errorf("Expected errors.Is to find the underlying cause")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/123", nil)
+	rec := httptest.NewRecorder()
+
+	Write(rec, req, NotFound("chirp_not_found", "Chirp not found"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want 404", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("got content type %q, want %q", got, ContentType)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if body["code"] != "chirp_not_found" || body["instance"] != "/api/chirps/123" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if _, ok := body["errors"]; ok {
+		t.Errorf("Expected errors to be omitted when there are no field errors")
+	}
+}
@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+func GetAPIKey(headers http.Header) (string, error) {
+	authHeader := headers.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("no authorization header included")
+	}
+	scheme, key, found := strings.Cut(authHeader, " ")
+	if !found || !strings.EqualFold(scheme, "ApiKey") {
+		return "", errors.New("malformed authorization header")
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", errors.New("malformed authorization header")
+	}
+	return key, nil
+}
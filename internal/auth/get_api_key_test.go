@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "valid api key",
+			header:  "ApiKey f271c81ff7084ee5b99a5091b42d486e",
+			want:    "f271c81ff7084ee5b99a5091b42d486e",
+			wantErr: false,
+		},
+		{
+			name:    "missing header",
+			header:  "",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "bearer scheme",
+			header:  "Bearer f271c81ff7084ee5b99a5091b42d486e",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "scheme without key",
+			header:  "ApiKey",
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+			got, gotErr := GetAPIKey(headers)
+			assertError(t, gotErr != nil, tt.wantErr)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
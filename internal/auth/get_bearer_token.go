@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+func GetBearerToken(headers http.Header) (string, error) {
+	authHeader := headers.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("no authorization header included")
+	}
+	scheme, token, found := strings.Cut(authHeader, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("malformed authorization header")
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("malformed authorization header")
+	}
+	return token, nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetBearerToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "valid bearer token",
+			header:  "Bearer abc.def.ghi",
+			want:    "abc.def.ghi",
+			wantErr: false,
+		},
+		{
+			name:    "lowercase scheme",
+			header:  "bearer abc.def.ghi",
+			want:    "abc.def.ghi",
+			wantErr: false,
+		},
+		{
+			name:    "missing header",
+			header:  "",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "wrong scheme",
+			header:  "Basic dXNlcjpwYXNz",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "scheme without token",
+			header:  "Bearer ",
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+			got, gotErr := GetBearerToken(headers)
+			assertError(t, gotErr != nil, tt.wantErr)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,52 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"strings"
+)
+
+// A PasswordHasher produces self-describing hashes that record the algorithm
+// and parameters used, so stored hashes keep verifying after the defaults
+// change.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, hash string) error
+	// Reports whether hash was produced by a different algorithm or with
+	// weaker parameters than this hasher would use today
+	NeedsRehash(hash string) bool
+}
+
+// Used for every new hash. Hashes made by other algorithms are still
+// verified and get upgraded through NeedsRehash.
+var DefaultHasher PasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
 
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return DefaultHasher.Hash(password)
+}
+
+// Verifies password against a hash produced by any supported algorithm
+func CheckPasswordHash(password, hash string) error {
+	hasher, err := hasherFor(hash)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return string(hashedPassword), nil
+	return hasher.Verify(password, hash)
+}
 
+// Reports whether hash should be replaced by a fresh one from DefaultHasher
+func NeedsRehash(hash string) bool {
+	return DefaultHasher.NeedsRehash(hash)
 }
 
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+func hasherFor(hash string) (PasswordHasher, error) {
+	switch {
+	case strings.HasPrefix(hash, argon2idPrefix):
+		return NewArgon2idHasher(DefaultArgon2idParams), nil
+	case isBcryptHash(hash):
+		return NewBcryptHasher(DefaultBcryptCost), nil
+	default:
+		return nil, ErrUnknownHashFormat
+	}
 }
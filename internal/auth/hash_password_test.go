@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPasswordUsesArgon2id(t *testing.T) {
+	hash, err := HashPassword("mypassword123")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Expected PHC formatted argon2id hash, got %q", hash)
+	}
+}
+
+func TestCrossAlgorithmVerification(t *testing.T) {
+	password := "correctpassword"
+	bcryptHash, err := NewBcryptHasher(DefaultBcryptCost).Hash(password)
+	if err != nil {
+		t.Fatalf("Failed to generate bcrypt hash: %v", err)
+	}
+	argonHash, err := NewArgon2idHasher(DefaultArgon2idParams).Hash(password)
+	if err != nil {
+		t.Fatalf("Failed to generate argon2id hash: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		wantErr  bool
+	}{
+		{
+			name:     "bcrypt hash with correct password",
+			password: password,
+			hash:     bcryptHash,
+			wantErr:  false,
+		},
+		{
+			name:     "bcrypt hash with wrong password",
+			password: "wrongpassword",
+			hash:     bcryptHash,
+			wantErr:  true,
+		},
+		{
+			name:     "argon2id hash with correct password",
+			password: password,
+			hash:     argonHash,
+			wantErr:  false,
+		},
+		{
+			name:     "argon2id hash with wrong password",
+			password: "wrongpassword",
+			hash:     argonHash,
+			wantErr:  true,
+		},
+		{
+			name:     "truncated argon2id hash",
+			password: password,
+			hash:     argonHash[:len(argonHash)-10] + "$",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown algorithm",
+			password: password,
+			hash:     "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := CheckPasswordHash(tt.password, tt.hash)
+			assertError(t, gotErr != nil, tt.wantErr)
+		})
+	}
+}
+
+func TestArgon2idRejectsDegenerateHashes(t *testing.T) {
+	password := "correctpassword"
+	hash, err := NewArgon2idHasher(DefaultArgon2idParams).Hash(password)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	withPart := func(i int, value string) string {
+		edited := append([]string{}, parts...)
+		edited[i] = value
+		return strings.Join(edited, "$")
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "zero memory", hash: withPart(3, "m=0,t=2,p=1")},
+		{name: "zero iterations", hash: withPart(3, "m=19456,t=0,p=1")},
+		{name: "zero parallelism", hash: withPart(3, "m=19456,t=2,p=0")},
+		{name: "empty salt", hash: withPart(4, "")},
+		{name: "empty key", hash: withPart(5, "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckPasswordHash(password, tt.hash); err == nil {
+				t.Errorf("CheckPasswordHash(%q) succeeded, want an error", tt.hash)
+			}
+			if err := CheckPasswordHash("wrongpassword", tt.hash); err == nil {
+				t.Errorf("CheckPasswordHash(%q) with a wrong password succeeded, want an error", tt.hash)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	password := "correctpassword"
+	weakParams := DefaultArgon2idParams
+	weakParams.Memory = 8 * 1024
+	weakParams.Iterations = 1
+
+	mustHash := func(h PasswordHasher) string {
+		t.Helper()
+		hash, err := h.Hash(password)
+		if err != nil {
+			t.Fatalf("Failed to hash password: %v", err)
+		}
+		return hash
+	}
+
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{
+			name:   "current default hash",
+			hasher: DefaultHasher,
+			hash:   mustHash(DefaultHasher),
+			want:   false,
+		},
+		{
+			name:   "bcrypt hash upgraded to argon2id",
+			hasher: DefaultHasher,
+			hash:   mustHash(NewBcryptHasher(DefaultBcryptCost)),
+			want:   true,
+		},
+		{
+			name:   "argon2id hash with weaker parameters",
+			hasher: DefaultHasher,
+			hash:   mustHash(NewArgon2idHasher(weakParams)),
+			want:   true,
+		},
+		{
+			name:   "argon2id hash with stronger parameters",
+			hasher: NewArgon2idHasher(weakParams),
+			hash:   mustHash(DefaultHasher),
+			want:   false,
+		},
+		{
+			name:   "bcrypt hash with lower cost",
+			hasher: NewBcryptHasher(DefaultBcryptCost),
+			hash:   mustHash(NewBcryptHasher(DefaultBcryptCost - 2)),
+			want:   true,
+		},
+		{
+			name:   "unparseable hash",
+			hasher: DefaultHasher,
+			hash:   "stuff",
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,12 +9,21 @@ import (
 	"github.com/google/uuid"
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   fmt.Sprintf("%v", userID),
+// The registered claims plus the caller's role
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Role Role `json:"role,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, role Role, tokenSecret string, expiresIn time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   fmt.Sprintf("%v", userID),
+		},
+		Role: role,
 	})
 	ss, err := token.SignedString([]byte(tokenSecret))
 	if err != nil {
@@ -23,30 +32,57 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return ss, nil
 }
 
+// The parts of a validated access token the server relies on
+type Claims struct {
+	UserID    uuid.UUID
+	Role      Role
+	ExpiresAt time.Time
+}
+
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID, nil
+}
+
+// Validates an access token like ValidateJWT and also returns the role and
+// when it expires. Tokens issued before roles existed carry RoleUser.
+func ParseJWT(tokenString, tokenSecret string) (Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &accessClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
 	if !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		return Claims{}, errors.New("invalid token")
 	}
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	claims, ok := token.Claims.(*accessClaims)
 	if !ok {
-		return uuid.Nil, fmt.Errorf("unexpected claim")
+		return Claims{}, fmt.Errorf("unexpected claim")
 	}
 	subject, err := claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("could not get subject from claims: %v", err)
+		return Claims{}, fmt.Errorf("could not get subject from claims: %v", err)
 	}
 	userID, err := uuid.Parse(subject)
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
+	}
+	if claims.ExpiresAt == nil {
+		return Claims{}, errors.New("token has no expiry")
+	}
+	role := RoleUser
+	if claims.Role != "" {
+		role, err = ParseRole(string(claims.Role))
+		if err != nil {
+			return Claims{}, err
+		}
 	}
-	return userID, nil
+	return Claims{UserID: userID, Role: role, ExpiresAt: claims.ExpiresAt.Time}, nil
 }
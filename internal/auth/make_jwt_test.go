@@ -16,7 +16,7 @@ func TestValidJWT(t *testing.T) {
 	tokenSecret := "your-test-secret"
 	expiresIn := time.Hour
 	// Act - Part 1: Create the token
-	token, err := MakeJWT(want, tokenSecret, expiresIn)
+	token, err := MakeJWT(want, RoleUser, tokenSecret, expiresIn)
 	if err != nil {
 		t.Fatalf("Error creating token: %v", err)
 	}
@@ -33,13 +33,73 @@ func TestValidJWT(t *testing.T) {
 
 }
 
+func TestParseJWTExpiry(t *testing.T) {
+	userID := uuid.New()
+	tokenSecret := "your-test-secret"
+	before := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	token, err := MakeJWT(userID, RoleUser, tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating token: %v", err)
+	}
+	claims, err := ParseJWT(token, tokenSecret)
+	if err != nil {
+		t.Fatalf("Error parsing token: %v", err)
+	}
+	if claims.UserID != userID {
+		t.Errorf("got user %v, want %v", claims.UserID, userID)
+	}
+	if claims.ExpiresAt.Before(before) || claims.ExpiresAt.After(before.Add(2*time.Second)) {
+		t.Errorf("got expiry %v, want about %v", claims.ExpiresAt, before)
+	}
+}
+
+func TestJWTRole(t *testing.T) {
+	tokenSecret := "your-test-secret"
+	tests := []struct {
+		name string
+		role Role
+		want Role
+	}{
+		{name: "user", role: RoleUser, want: RoleUser},
+		{name: "moderator", role: RoleModerator, want: RoleModerator},
+		{name: "admin", role: RoleAdmin, want: RoleAdmin},
+		{name: "no role claim", role: "", want: RoleUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := MakeJWT(uuid.New(), tt.role, tokenSecret, time.Hour)
+			if err != nil {
+				t.Fatalf("Error creating token: %v", err)
+			}
+			claims, err := ParseJWT(token, tokenSecret)
+			if err != nil {
+				t.Fatalf("Error parsing token: %v", err)
+			}
+			if claims.Role != tt.want {
+				t.Errorf("got role %q, want %q", claims.Role, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown role", func(t *testing.T) {
+		token, err := MakeJWT(uuid.New(), Role("root"), tokenSecret, time.Hour)
+		if err != nil {
+			t.Fatalf("Error creating token: %v", err)
+		}
+		if _, err := ParseJWT(token, tokenSecret); err == nil {
+			t.Fatal("Expected a token with an unknown role to be rejected")
+		}
+	})
+}
+
 func TestExpiredJWT(t *testing.T) {
 	userID := uuid.New()
 	tokenSecret := "your-test-secret"
 
 	t.Run("negative duration", func(t *testing.T) {
 		expiresIn := -time.Hour
-		token, err := MakeJWT(userID, tokenSecret, expiresIn)
+		token, err := MakeJWT(userID, RoleUser, tokenSecret, expiresIn)
 		if err != nil {
 			t.Fatalf("Error creating token: %v", err)
 		}
@@ -50,7 +110,7 @@ func TestExpiredJWT(t *testing.T) {
 
 	t.Run("natural expiration", func(t *testing.T) {
 		expiresIn := time.Second
-		token, err := MakeJWT(userID, tokenSecret, expiresIn)
+		token, err := MakeJWT(userID, RoleUser, tokenSecret, expiresIn)
 		if err != nil {
 			t.Fatalf("Error creating token: %v", err)
 		}
@@ -66,7 +126,7 @@ func TestInvalidSecretJWT(t *testing.T) {
 	tokenSecret := "correct-secret"
 	expiresIn := time.Hour
 
-	token, err := MakeJWT(userId, tokenSecret, expiresIn)
+	token, err := MakeJWT(userId, RoleUser, tokenSecret, expiresIn)
 	if err != nil {
 		t.Fatalf("Error creating token: %v", err)
 	}
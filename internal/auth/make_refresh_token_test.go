@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestMakeRefreshToken(t *testing.T) {
+	first, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("Error creating refresh token: %v", err)
+	}
+	if len(first) != 64 {
+		t.Errorf("got token length %d, want 64", len(first))
+	}
+	if _, err := hex.DecodeString(first); err != nil {
+		t.Errorf("Expected hex encoded token, got %q", first)
+	}
+
+	second, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("Error creating refresh token: %v", err)
+	}
+	if first == second {
+		t.Errorf("Expected two refresh tokens to differ, both were %q", first)
+	}
+}
@@ -0,0 +1,52 @@
+package auth
+
+import "fmt"
+
+// What a user is allowed to do. Each role can do everything the roles
+// below it can.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// Reports whether r grants everything min does. Unknown roles grant
+// nothing.
+func (r Role) AtLeast(min Role) bool {
+	rank, ok := roleRanks[r]
+	if !ok {
+		return false
+	}
+	return rank >= roleRanks[min]
+}
+
+// Reports whether r ranks strictly above other, i.e. whether someone with
+// role r may act against someone with role other. Unknown roles outrank
+// nothing and are outranked by nothing.
+func (r Role) Outranks(other Role) bool {
+	rank, ok := roleRanks[r]
+	if !ok {
+		return false
+	}
+	otherRank, ok := roleRanks[other]
+	if !ok {
+		return false
+	}
+	return rank > otherRank
+}
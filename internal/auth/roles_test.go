@@ -0,0 +1,77 @@
+package auth
+
+import "testing"
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Role
+		wantErr bool
+	}{
+		{input: "user", want: RoleUser},
+		{input: "moderator", want: RoleModerator},
+		{input: "admin", want: RoleAdmin},
+		{input: "Admin", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "root", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseRole(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role Role
+		min  Role
+		want bool
+	}{
+		{role: RoleUser, min: RoleUser, want: true},
+		{role: RoleUser, min: RoleModerator, want: false},
+		{role: RoleModerator, min: RoleModerator, want: true},
+		{role: RoleModerator, min: RoleAdmin, want: false},
+		{role: RoleAdmin, min: RoleModerator, want: true},
+		{role: RoleAdmin, min: RoleAdmin, want: true},
+		{role: Role("root"), min: RoleUser, want: false},
+		{role: Role(""), min: RoleUser, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+">="+string(tt.min), func(t *testing.T) {
+			if got := tt.role.AtLeast(tt.min); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleOutranks(t *testing.T) {
+	tests := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{role: RoleModerator, other: RoleUser, want: true},
+		{role: RoleModerator, other: RoleModerator, want: false},
+		{role: RoleModerator, other: RoleAdmin, want: false},
+		{role: RoleAdmin, other: RoleModerator, want: true},
+		{role: RoleAdmin, other: RoleAdmin, want: false},
+		{role: RoleUser, other: RoleUser, want: false},
+		{role: RoleAdmin, other: Role("root"), want: false},
+		{role: Role("root"), other: RoleUser, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+">"+string(tt.other), func(t *testing.T) {
+			if got := tt.role.Outranks(tt.other); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
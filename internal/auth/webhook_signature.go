@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const signaturePrefix = "sha256="
+
+// Returns the HMAC-SHA256 signature of payload in the form "sha256=<hex>"
+func SignPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifySignature(payload []byte, secret, signature string) error {
+	if secret == "" {
+		return errors.New("no signing secret configured")
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return errors.New("malformed signature")
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return errors.New("malformed signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
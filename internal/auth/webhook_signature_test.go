@@ -0,0 +1,67 @@
+package auth
+
+import "testing"
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"event":"user.upgraded"}`)
+	secret := "webhook-secret"
+	signature := SignPayload(payload, secret)
+
+	tests := []struct {
+		name      string
+		payload   []byte
+		secret    string
+		signature string
+		wantErr   bool
+	}{
+		{
+			name:      "matching signature",
+			payload:   payload,
+			secret:    secret,
+			signature: signature,
+			wantErr:   false,
+		},
+		{
+			name:      "tampered payload",
+			payload:   []byte(`{"event":"user.downgraded"}`),
+			secret:    secret,
+			signature: signature,
+			wantErr:   true,
+		},
+		{
+			name:      "wrong secret",
+			payload:   payload,
+			secret:    "other-secret",
+			signature: signature,
+			wantErr:   true,
+		},
+		{
+			name:      "empty secret",
+			payload:   payload,
+			secret:    "",
+			signature: SignPayload(payload, ""),
+			wantErr:   true,
+		},
+		{
+			name:      "missing prefix",
+			payload:   payload,
+			secret:    secret,
+			signature: signature[len("sha256="):],
+			wantErr:   true,
+		},
+		{
+			name:      "not hex",
+			payload:   payload,
+			secret:    secret,
+			signature: "sha256=zzzz",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErr := VerifySignature(tt.payload, tt.secret, tt.signature)
+			assertError(t, gotErr != nil, tt.wantErr)
+		})
+	}
+}
@@ -7,23 +7,29 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, search_vector, hidden_at
 `
 
 type CreateChirpParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.NullUUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.NullUUID
+	InReplyToID  uuid.NullUUID
+	ThreadRootID uuid.UUID
+	RechirpOfID  uuid.NullUUID
+	QuoteOfID    uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
@@ -33,6 +39,10 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		arg.UpdatedAt,
 		arg.Body,
 		arg.UserID,
+		arg.InReplyToID,
+		arg.ThreadRootID,
+		arg.RechirpOfID,
+		arg.QuoteOfID,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -41,18 +51,83 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.InReplyToID,
+		&i.ThreadRootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.SearchVector,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id
+const getChirp = `-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, search_vector, hidden_at
 FROM chirps
-ORDER BY created_at ASC
+WHERE chirps.id = $1 AND deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = $2::uuid AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+type GetChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirp(ctx context.Context, arg GetChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.InReplyToID,
+		&i.ThreadRootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.SearchVector,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpAuthorID = `-- name: GetChirpAuthorID :one
+SELECT user_id
+FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpAuthorID(ctx context.Context, id uuid.UUID) (uuid.NullUUID, error) {
+	row := q.db.QueryRowContext(ctx, getChirpAuthorID, id)
+	var user_id uuid.NullUUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, search_vector, hidden_at
+FROM chirps
+WHERE id = ANY($1::uuid[])
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +141,13 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.DeletedAt,
+			&i.InReplyToID,
+			&i.ThreadRootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.SearchVector,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -80,14 +162,19 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+const getRechirpByUser = `-- name: GetRechirpByUser :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, search_vector, hidden_at
 FROM chirps
-WHERE ID = $1
+WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+type GetRechirpByUserParams struct {
+	UserID      uuid.NullUUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) GetRechirpByUser(ctx context.Context, arg GetRechirpByUserParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirpByUser, arg.UserID, arg.RechirpOfID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -95,6 +182,408 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.InReplyToID,
+		&i.ThreadRootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.SearchVector,
+		&i.HiddenAt,
 	)
 	return i, err
 }
+
+const getRechirpCounts = `-- name: GetRechirpCounts :many
+SELECT
+  target_id::uuid AS chirp_id,
+  COUNT(*) FILTER (WHERE kind = 'rechirp')::int AS rechirp_count,
+  COUNT(*) FILTER (WHERE kind = 'quote')::int AS quote_count
+FROM (
+    SELECT rechirp_of_id AS target_id, 'rechirp' AS kind
+    FROM chirps
+    WHERE rechirp_of_id = ANY($1::uuid[]) AND deleted_at IS NULL
+  UNION ALL
+    SELECT quote_of_id AS target_id, 'quote' AS kind
+    FROM chirps
+    WHERE quote_of_id = ANY($1::uuid[]) AND deleted_at IS NULL
+) AS reposts
+GROUP BY target_id
+`
+
+type GetRechirpCountsRow struct {
+	ChirpID      uuid.UUID
+	RechirpCount int32
+	QuoteCount   int32
+}
+
+func (q *Queries) GetRechirpCounts(ctx context.Context, ids []uuid.UUID) ([]GetRechirpCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpCounts, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRechirpCountsRow
+	for rows.Next() {
+		var i GetRechirpCountsRow
+		if err := rows.Scan(&i.ChirpID, &i.RechirpCount, &i.QuoteCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThread = `-- name: GetThread :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.deleted_at, chirps.in_reply_to_id, chirps.thread_root_id, chirps.rechirp_of_id, chirps.quote_of_id, chirps.search_vector, chirps.hidden_at, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = $2
+  UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.deleted_at, chirps.in_reply_to_id, chirps.thread_root_id, chirps.rechirp_of_id, chirps.quote_of_id, chirps.search_vector, chirps.hidden_at, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.in_reply_to_id = thread.id
+    WHERE chirps.thread_root_id = $2
+)
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, depth::int AS depth,
+  (
+    thread.hidden_at IS NOT NULL
+    OR EXISTS (SELECT 1 FROM users WHERE users.id = thread.user_id AND users.suspended_at IS NOT NULL)
+    OR EXISTS (
+      SELECT 1 FROM hidden_authors
+      WHERE hidden_authors.viewer_id = $1::uuid AND hidden_authors.author_id = thread.user_id
+    )
+  )::bool AS withheld
+FROM thread
+ORDER BY depth ASC, created_at ASC, id ASC
+`
+
+type GetThreadParams struct {
+	ViewerID uuid.NullUUID
+	RootID   uuid.UUID
+}
+
+type GetThreadRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.NullUUID
+	DeletedAt    sql.NullTime
+	InReplyToID  uuid.NullUUID
+	ThreadRootID uuid.UUID
+	RechirpOfID  uuid.NullUUID
+	QuoteOfID    uuid.NullUUID
+	Depth        int32
+	Withheld     bool
+}
+
+func (q *Queries) GetThread(ctx context.Context, arg GetThreadParams) ([]GetThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, arg.ViewerID, arg.RootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetThreadRow
+	for rows.Next() {
+		var i GetThreadRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.InReplyToID,
+			&i.ThreadRootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.Depth,
+			&i.Withheld,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.deleted_at, chirps.in_reply_to_id, chirps.thread_root_id, chirps.rechirp_of_id, chirps.quote_of_id, chirps.search_vector, chirps.hidden_at
+FROM chirps
+WHERE chirps.user_id IN (
+    SELECT followee_id FROM follows WHERE follower_id = $1
+  )
+  AND chirps.deleted_at IS NULL
+  AND (
+    $2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = $1 AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	FollowerID      uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.FollowerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.InReplyToID,
+			&i.ThreadRootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, hideChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, search_vector, hidden_at
+FROM chirps
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = $4::uuid AND hidden_authors.author_id = user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type ListChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.InReplyToID,
+			&i.ThreadRootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, search_vector, hidden_at
+FROM chirps
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = $4::uuid AND hidden_authors.author_id = user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.InReplyToID,
+			&i.ThreadRootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.SearchVector,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreChirp = `-- name: RestoreChirp :execrows
+UPDATE chirps
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const unhideChirp = `-- name: UnhideChirp :execrows
+UPDATE chirps
+SET hidden_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) UnhideChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unhideChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
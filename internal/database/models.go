@@ -5,17 +5,148 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditLog struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ActorID    uuid.NullUUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Details    json.RawMessage
+}
+
+type BannedWord struct {
+	Word      string
+	Action    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.NullUUID
+	DeletedAt    sql.NullTime
+	InReplyToID  uuid.NullUUID
+	ThreadRootID uuid.UUID
+	RechirpOfID  uuid.NullUUID
+	QuoteOfID    uuid.NullUUID
+	SearchVector interface{}
+	HiddenAt     sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID     uuid.UUID
+	HashtagID   uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.NullUUID
+	CreatedBy uuid.NullUUID
+	DirectKey sql.NullString
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+type HiddenAuthor struct {
+	ViewerID uuid.UUID
+	AuthorID uuid.UUID
+}
+
+type Mention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Kind      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+}
+
+type Report struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	ChirpID       uuid.UUID
+	ReporterID    uuid.NullUUID
+	Reason        string
+	Details       string
+	Status        string
+	Decision      sql.NullString
+	ModeratorNote string
+	ResolvedAt    sql.NullTime
 }
 
 type User struct {
@@ -24,4 +155,14 @@ type User struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
+	IsChirpyRed    bool
+	Handle         sql.NullString
+	SuspendedAt    sql.NullTime
+	Role           string
+}
+
+type WebhookEvent struct {
+	ID         string
+	ReceivedAt time.Time
+	Event      string
 }
@@ -0,0 +1,113 @@
+// Package entities finds #hashtags and @mentions in chirp bodies.
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Handles are ASCII only so they can be typed on any keyboard
+const MaxHandleLength = 15
+
+// A #hashtag found in a text. Offsets count runes, not bytes; Start is the
+// position of the '#' and End is exclusive.
+type Hashtag struct {
+	Tag   string
+	Start int
+	End   int
+}
+
+// A @mention found in a text. Handle is as written, without the '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+type Entities struct {
+	Hashtags []Hashtag
+	Mentions []Mention
+}
+
+// Finds every hashtag and mention in text. A '#' or '@' only starts an
+// entity at the beginning of the text or after a rune that cannot be part
+// of a word, so "a#b" and "me@example.com" are ignored.
+func Parse(text string) Entities {
+	runes := []rune(text)
+	var found Entities
+	for i := 0; i < len(runes); i++ {
+		if i > 0 && (isTagRune(runes[i-1]) || runes[i-1] == '#' || runes[i-1] == '@') {
+			continue
+		}
+		switch runes[i] {
+		case '#':
+			end := i + 1
+			for end < len(runes) && isTagRune(runes[end]) {
+				end++
+			}
+			tag := string(runes[i+1 : end])
+			if ValidTag(tag) {
+				found.Hashtags = append(found.Hashtags, Hashtag{Tag: NormalizeTag(tag), Start: i, End: end})
+			}
+			i = end - 1
+		case '@':
+			end := i + 1
+			for end < len(runes) && isTagRune(runes[end]) {
+				end++
+			}
+			handle := string(runes[i+1 : end])
+			if ValidHandle(handle) && (end == len(runes) || runes[end] != '@') {
+				found.Mentions = append(found.Mentions, Mention{Handle: handle, Start: i, End: end})
+			}
+			i = end - 1
+		}
+	}
+	return found
+}
+
+// Reports whether tag, without the '#', is a usable hashtag. Tags made up
+// only of digits are rejected so "#1" stays plain text.
+func ValidTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	hasNonDigit := false
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return false
+		}
+		if !unicode.IsDigit(r) {
+			hasNonDigit = true
+		}
+	}
+	return hasNonDigit
+}
+
+// Returns the form a hashtag is stored and looked up by
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// Reports whether handle, without the '@', is a valid user handle:
+// 1 to MaxHandleLength ASCII letters, digits or underscores.
+func ValidHandle(handle string) bool {
+	if handle == "" || len(handle) > MaxHandleLength {
+		return false
+	}
+	for _, r := range handle {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the form a handle is compared by. Handles keep the case they
+// were registered with but are unique regardless of case.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
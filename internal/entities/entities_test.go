@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Entities
+	}{
+		{
+			name: "plain text",
+			text: "nothing to see here",
+			want: Entities{},
+		},
+		{
+			name: "hashtag and mention",
+			text: "hey @Lane check #Golang",
+			want: Entities{
+				Hashtags: []Hashtag{{Tag: "golang", Start: 16, End: 23}},
+				Mentions: []Mention{{Handle: "Lane", Start: 4, End: 9}},
+			},
+		},
+		{
+			name: "offsets count runes",
+			text: "café #naïve",
+			want: Entities{
+				Hashtags: []Hashtag{{Tag: "naïve", Start: 5, End: 11}},
+			},
+		},
+		{
+			name: "trailing punctuation",
+			text: "#go! @boots, ok",
+			want: Entities{
+				Hashtags: []Hashtag{{Tag: "go", Start: 0, End: 3}},
+				Mentions: []Mention{{Handle: "boots", Start: 5, End: 11}},
+			},
+		},
+		{
+			name: "inside a word",
+			text: "a#b me@example.com",
+			want: Entities{},
+		},
+		{
+			name: "digits only",
+			text: "we're #1",
+			want: Entities{},
+		},
+		{
+			name: "handle too long",
+			text: "@abcdefghijklmnopq",
+			want: Entities{},
+		},
+		{
+			name: "repeated symbol",
+			text: "##go @@boots",
+			want: Entities{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   bool
+	}{
+		{handle: "boots", want: true},
+		{handle: "Lane_Wagner", want: true},
+		{handle: "", want: false},
+		{handle: "has space", want: false},
+		{handle: "ünicode", want: false},
+		{handle: "abcdefghijklmnop", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			if got := ValidHandle(tt.handle); got != tt.want {
+				t.Errorf("ValidHandle(%q) = %v, want %v", tt.handle, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package lockout tracks failed attempts per key (an account, a client IP)
+// and locks keys out with exponential backoff once they exceed a number of
+// free attempts.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists failure counters and locks. MemoryStore works for a single
+// instance; multi-instance deployments should plug in a shared store.
+type Store interface {
+	// Increments the failure count for key and returns the new count. The
+	// count starts over when the previous failure is older than window.
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	SetLockedUntil(ctx context.Context, key string, until time.Time) error
+	// Returns the zero time when key is not locked
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type Policy struct {
+	// Failures allowed before the first lock
+	FreeAttempts int
+	// Length of the first lock, doubled for every further failure
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Failures older than this are forgotten
+	Window time.Duration
+}
+
+// Returns how long a key with the given number of failures stays locked
+func (p Policy) Delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < over; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, now: time.Now}
+}
+
+// Returns how long the caller must wait before key may be tried again,
+// zero when it is not locked
+func (l *Limiter) RetryAfter(ctx context.Context, key string) (time.Duration, error) {
+	until, err := l.store.LockedUntil(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if wait := until.Sub(l.now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Records a failed attempt and locks key if it has run out of free attempts
+func (l *Limiter) Fail(ctx context.Context, key string) error {
+	failures, err := l.store.AddFailure(ctx, key, l.policy.Window)
+	if err != nil {
+		return err
+	}
+	delay := l.policy.Delay(failures)
+	if delay == 0 {
+		return nil
+	}
+	return l.store.SetLockedUntil(ctx, key, l.now().Add(delay))
+}
+
+// Forgets all failures and any lock for key
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, key)
+}
@@ -0,0 +1,123 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestLimiter(policy Policy) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.now
+	limiter := NewLimiter(store, policy)
+	limiter.now = clock.now
+	return limiter, clock
+}
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     10 * time.Second,
+	Window:       time.Hour,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 6, want: 4 * time.Second},
+		{failures: 7, want: 8 * time.Second},
+		{failures: 8, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterLocksAfterFreeAttempts(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := newTestLimiter(testPolicy)
+	key := "email:user@example.com"
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		if err := limiter.Fail(ctx, key); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+		if wait, _ := limiter.RetryAfter(ctx, key); wait != 0 {
+			t.Fatalf("Expected no lock after %d failures, got %v", i+1, wait)
+		}
+	}
+
+	limiter.Fail(ctx, key)
+	if wait, _ := limiter.RetryAfter(ctx, key); wait != time.Second {
+		t.Fatalf("got wait %v, want %v", wait, time.Second)
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	if wait, _ := limiter.RetryAfter(ctx, key); wait != 0 {
+		t.Fatalf("Expected lock to expire, got wait %v", wait)
+	}
+
+	limiter.Fail(ctx, key)
+	if wait, _ := limiter.RetryAfter(ctx, key); wait != 2*time.Second {
+		t.Fatalf("got wait %v, want %v", wait, 2*time.Second)
+	}
+}
+
+func TestLimiterReset(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := newTestLimiter(testPolicy)
+	key := "ip:203.0.113.7"
+
+	for i := 0; i < testPolicy.FreeAttempts+2; i++ {
+		limiter.Fail(ctx, key)
+	}
+	if wait, _ := limiter.RetryAfter(ctx, key); wait == 0 {
+		t.Fatalf("Expected key to be locked")
+	}
+
+	if err := limiter.Reset(ctx, key); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if wait, _ := limiter.RetryAfter(ctx, key); wait != 0 {
+		t.Fatalf("Expected key to be unlocked after reset, got wait %v", wait)
+	}
+	limiter.Fail(ctx, key)
+	if wait, _ := limiter.RetryAfter(ctx, key); wait != 0 {
+		t.Fatalf("Expected failure count to restart after reset, got wait %v", wait)
+	}
+}
+
+func TestFailuresOutsideWindowAreForgotten(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := newTestLimiter(testPolicy)
+	key := "email:user@example.com"
+
+	for i := 0; i < testPolicy.FreeAttempts; i++ {
+		limiter.Fail(ctx, key)
+	}
+	clock.t = clock.t.Add(testPolicy.Window + time.Minute)
+
+	limiter.Fail(ctx, key)
+	if wait, _ := limiter.RetryAfter(ctx, key); wait != 0 {
+		t.Fatalf("Expected stale failures to be forgotten, got wait %v", wait)
+	}
+}
@@ -0,0 +1,86 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Entries are swept once the map grows past this size
+const sweepThreshold = 10000
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	window      time.Duration
+}
+
+// An in-process Store. State is lost on restart and not shared between
+// instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry), now: time.Now}
+}
+
+func (s *MemoryStore) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if len(s.entries) >= sweepThreshold {
+		s.sweep(now)
+	}
+	e, ok := s.entries[key]
+	if !ok || now.Sub(e.lastFailure) > window {
+		e = &entry{}
+		s.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+	e.window = window
+	return e.failures, nil
+}
+
+func (s *MemoryStore) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{lastFailure: s.now()}
+		s.entries[key] = e
+	}
+	e.lockedUntil = until
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	return e.lockedUntil, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Drops entries whose failures have aged out and that are no longer locked.
+// Must be called with mu held.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if now.Sub(e.lastFailure) > e.window && now.After(e.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
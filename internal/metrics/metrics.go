@@ -0,0 +1,395 @@
+// Package metrics is a small in-process metrics registry. Counters, gauges
+// and histograms are registered on a Registry, which renders them in the
+// Prometheus text exposition format and can also be read back as a
+// snapshot, e.g. for an HTML status page.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types as named in the exposition format
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Bucket upper bounds suited to request latencies in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Returns count bucket upper bounds starting at start, each factor times
+// the one before
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// A snapshot of one metric and all of its series
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// One value of a family. Histograms produce several samples per series,
+// named with the _bucket, _sum and _count suffixes.
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Returns the value of the label called name, or "" if there is none
+func (s Sample) Label(name string) string {
+	for _, l := range s.Labels {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+type collector interface {
+	family() Family
+	reset()
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Registering two metrics under the same name is a programming error and
+// panics
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[name]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.collectors[name] = c
+}
+
+// Returns every registered metric sorted by name
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+
+	families := make([]Family, len(collectors))
+	for i, c := range collectors {
+		families[i] = c.family()
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// Zeroes every counter and histogram. Gauges describe current state and
+// are left alone.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.collectors {
+		c.reset()
+	}
+}
+
+// A float64 that can be updated from several goroutines
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) set(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// The series of one metric, keyed by their label values
+type vec[T any] struct {
+	name       string
+	help       string
+	labelNames []string
+	newSeries  func() *T
+
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+}
+
+// A metric without labels has its one series from the start, so it is
+// exposed as zero before it is first updated
+func newVec[T any](name, help string, labelNames []string, newSeries func() *T) *vec[T] {
+	v := &vec[T]{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		newSeries:  newSeries,
+		series:     make(map[string]*T),
+		values:     make(map[string][]string),
+	}
+	if len(labelNames) == 0 {
+		v.with(nil)
+	}
+	return v
+}
+
+// Returns the series for labelValues, creating it on first use. Passing
+// the wrong number of values is a programming error and panics.
+func (v *vec[T]) with(labelValues []string) *T {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newSeries()
+		v.series[key] = s
+		v.values[key] = slices.Clone(labelValues)
+	}
+	return s
+}
+
+// Calls fn for every series in a stable order
+func (v *vec[T]) each(fn func(labels []Label, s *T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := make([]*T, len(keys))
+	labels := make([][]Label, len(keys))
+	for i, key := range keys {
+		series[i] = v.series[key]
+		labels[i] = make([]Label, len(v.labelNames))
+		for j, name := range v.labelNames {
+			labels[i][j] = Label{Name: name, Value: v.values[key][j]}
+		}
+	}
+	v.mu.Unlock()
+
+	for i := range series {
+		fn(labels[i], series[i])
+	}
+}
+
+// A value that only goes up, split into series by its labels
+type Counter struct {
+	vec *vec[atomicFloat]
+}
+
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, labelNames, func() *atomicFloat { return &atomicFloat{} })}
+	r.register(name, c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Negative deltas are ignored
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.vec.with(labelValues).add(delta)
+}
+
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.vec.with(labelValues).load()
+}
+
+func (c *Counter) family() Family {
+	return valueFamily(c.vec, TypeCounter)
+}
+
+func (c *Counter) reset() {
+	c.vec.each(func(_ []Label, s *atomicFloat) { s.set(0) })
+}
+
+// A value that can go up and down, split into series by its labels
+type Gauge struct {
+	vec *vec[atomicFloat]
+}
+
+func (r *Registry) NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{vec: newVec(name, help, labelNames, func() *atomicFloat { return &atomicFloat{} })}
+	r.register(name, g)
+	return g
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.with(labelValues).set(v)
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.vec.with(labelValues).add(1)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.vec.with(labelValues).add(-1)
+}
+
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.vec.with(labelValues).load()
+}
+
+func (g *Gauge) family() Family {
+	return valueFamily(g.vec, TypeGauge)
+}
+
+func (g *Gauge) reset() {}
+
+func valueFamily(v *vec[atomicFloat], typ string) Family {
+	f := Family{Name: v.name, Help: v.help, Type: typ}
+	v.each(func(labels []Label, s *atomicFloat) {
+		f.Samples = append(f.Samples, Sample{Name: v.name, Labels: labels, Value: s.load()})
+	})
+	return f
+}
+
+// A metric whose single value is read from fn whenever the registry is
+// gathered, for state that is tracked elsewhere such as sql.DB.Stats
+type funcMetric struct {
+	name string
+	help string
+	typ  string
+	fn   func() float64
+}
+
+// Registers a gauge read from fn
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: TypeGauge, fn: fn})
+}
+
+// Registers a counter read from fn, which must never decrease
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: TypeCounter, fn: fn})
+}
+
+func (m *funcMetric) family() Family {
+	return Family{
+		Name:    m.name,
+		Help:    m.help,
+		Type:    m.typ,
+		Samples: []Sample{{Name: m.name, Value: m.fn()}},
+	}
+}
+
+func (m *funcMetric) reset() {}
+
+// Counts observations into buckets by their value, split into series by
+// its labels
+type Histogram struct {
+	buckets []float64
+	vec     *vec[histogramSeries]
+}
+
+type histogramSeries struct {
+	mu sync.Mutex
+	// Per-bucket rather than cumulative counts, with a final +Inf bucket
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// buckets are upper bounds in increasing order. The +Inf bucket is added
+// automatically.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+	h := &Histogram{buckets: slices.Clone(buckets)}
+	h.vec = newVec(name, help, labelNames, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(h.buckets)+1)}
+	})
+	r.register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	s := h.vec.with(labelValues)
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+func (h *Histogram) family() Family {
+	f := Family{Name: h.vec.name, Help: h.vec.help, Type: TypeHistogram}
+	h.vec.each(func(labels []Label, s *histogramSeries) {
+		s.mu.Lock()
+		counts := slices.Clone(s.counts)
+		sum, count := s.sum, s.count
+		s.mu.Unlock()
+
+		var cumulative uint64
+		for i, n := range counts {
+			cumulative += n
+			le := math.Inf(1)
+			if i < len(h.buckets) {
+				le = h.buckets[i]
+			}
+			f.Samples = append(f.Samples, Sample{
+				Name:   h.vec.name + "_bucket",
+				Labels: append(slices.Clone(labels), Label{Name: "le", Value: formatValue(le)}),
+				Value:  float64(cumulative),
+			})
+		}
+		f.Samples = append(f.Samples,
+			Sample{Name: h.vec.name + "_sum", Labels: labels, Value: sum},
+			Sample{Name: h.vec.name + "_count", Labels: labels, Value: float64(count)},
+		)
+	})
+	return f
+}
+
+func (h *Histogram) reset() {
+	h.vec.each(func(_ []Label, s *histogramSeries) {
+		s.mu.Lock()
+		clear(s.counts)
+		s.sum, s.count = 0, 0
+		s.mu.Unlock()
+	})
+}
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(r *Registry)
+		want  string
+	}{
+		{
+			name: "counter with labels",
+			setup: func(r *Registry) {
+				c := r.NewCounter("requests_total", "Requests served.", "method", "code")
+				c.Inc("GET", "200")
+				c.Add(2, "GET", "200")
+				c.Inc("POST", "201")
+				c.Add(-5, "POST", "201")
+			},
+			want: `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",code="200"} 3
+requests_total{method="POST",code="201"} 1
+`,
+		},
+		{
+			name: "gauge without labels",
+			setup: func(r *Registry) {
+				g := r.NewGauge("in_flight", "Requests in flight.")
+				g.Inc()
+				g.Inc()
+				g.Dec()
+			},
+			want: `# HELP in_flight Requests in flight.
+# TYPE in_flight gauge
+in_flight 1
+`,
+		},
+		{
+			name: "histogram buckets are cumulative",
+			setup: func(r *Registry) {
+				h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+				h.Observe(0.05, "/a")
+				h.Observe(0.1, "/a")
+				h.Observe(0.5, "/a")
+				h.Observe(3, "/a")
+			},
+			want: `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 3.65
+latency_seconds_count{route="/a"} 4
+`,
+		},
+		{
+			name: "func metrics and sorting",
+			setup: func(r *Registry) {
+				r.NewGaugeFunc("b_open", "Open connections.", func() float64 { return 4 })
+				r.NewCounterFunc("a_waits_total", "Waits.", func() float64 { return 7 })
+			},
+			want: `# HELP a_waits_total Waits.
+# TYPE a_waits_total counter
+a_waits_total 7
+# HELP b_open Open connections.
+# TYPE b_open gauge
+b_open 4
+`,
+		},
+		{
+			name: "unlabeled metrics start at zero",
+			setup: func(r *Registry) {
+				r.NewCounter("created_total", "Created.")
+			},
+			want: `# HELP created_total Created.
+# TYPE created_total counter
+created_total 0
+`,
+		},
+		{
+			name: "escaping",
+			setup: func(r *Registry) {
+				c := r.NewCounter("escaped_total", "Line one\nback\\slash", "path")
+				c.Inc(`/a"b\c`)
+			},
+			want: `# HELP escaped_total Line one\nback\\slash
+# TYPE escaped_total counter
+escaped_total{path="/a\"b\\c"} 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.setup(r)
+			var sb strings.Builder
+			if err := r.WriteText(&sb); err != nil {
+				t.Fatalf("WriteText() error = %v", err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("WriteText() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("hits_total", "Hits.")
+	g := r.NewGauge("in_flight", "In flight.")
+	h := r.NewHistogram("size_bytes", "Size.", []float64{10})
+	c.Add(3)
+	g.Set(2)
+	h.Observe(5)
+
+	r.Reset()
+
+	if got := c.Value(); got != 0 {
+		t.Errorf("counter after Reset = %v, want 0", got)
+	}
+	if got := g.Value(); got != 2 {
+		t.Errorf("gauge after Reset = %v, want 2", got)
+	}
+	for _, f := range r.Gather() {
+		if f.Name != "size_bytes" {
+			continue
+		}
+		for _, s := range f.Samples {
+			if s.Value != 0 {
+				t.Errorf("%s after Reset = %v, want 0", s.Name, s.Value)
+			}
+		}
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.")
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	r.NewGauge("hits_total", "Hits.")
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.").Inc()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "hits_total 1\n") {
+		t.Errorf("body = %q, want it to contain the counter", rec.Body.String())
+	}
+}
@@ -0,0 +1,91 @@
+// Package moderation checks user submitted text against a chain of filters
+// (word lists, regex rules) and decides whether to mask offending content,
+// reject the text outright or flag it for human review.
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+type Action string
+
+const (
+	// Replace the offending content with asterisks
+	ActionMask Action = "mask"
+	// Refuse the text entirely
+	ActionReject Action = "reject"
+	// Accept the text unchanged but queue it for a moderator
+	ActionFlag Action = "flag"
+)
+
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case ActionMask, ActionReject, ActionFlag:
+		return Action(s), nil
+	default:
+		return "", fmt.Errorf("unknown moderation action %q", s)
+	}
+}
+
+// A single rule that fired on a piece of text
+type Match struct {
+	Rule   string
+	Action Action
+}
+
+type Filter interface {
+	// Returns text with every masked match replaced, plus all matches found
+	Apply(text string) (string, []Match)
+}
+
+var ErrTooLong = errors.New("text is too long")
+
+type RejectedError struct {
+	Rule string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("text rejected by rule %q", e.Rule)
+}
+
+type Result struct {
+	Text    string
+	Flagged bool
+	// Rules that caused the text to be flagged
+	Reasons []string
+}
+
+type Moderator struct {
+	maxLength int
+	filters   []Filter
+}
+
+// maxLength is counted in runes, not bytes
+func NewModerator(maxLength int, filters ...Filter) *Moderator {
+	return &Moderator{maxLength: maxLength, filters: filters}
+}
+
+// Runs text through every filter in order. Returns ErrTooLong or a
+// *RejectedError when the text must not be accepted.
+func (m *Moderator) Moderate(text string) (Result, error) {
+	if utf8.RuneCountInString(text) > m.maxLength {
+		return Result{}, ErrTooLong
+	}
+	result := Result{Text: text}
+	for _, filter := range m.filters {
+		masked, matches := filter.Apply(result.Text)
+		for _, match := range matches {
+			switch match.Action {
+			case ActionReject:
+				return Result{}, &RejectedError{Rule: match.Rule}
+			case ActionFlag:
+				result.Flagged = true
+				result.Reasons = append(result.Reasons, match.Rule)
+			}
+		}
+		result.Text = masked
+	}
+	return result, nil
+}
@@ -0,0 +1,194 @@
+package moderation
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newTestModerator() *Moderator {
+	words := NewWordList(
+		Word{Word: "kerfuffle", Action: ActionMask},
+		Word{Word: "sharbert", Action: ActionMask},
+		Word{Word: "fornax", Action: ActionMask},
+		Word{Word: "scam", Action: ActionFlag},
+		Word{Word: "slur", Action: ActionReject},
+	)
+	rules := NewRegexFilter(RegexRule{
+		Name:    "phone-number",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{3}-\d{4}\b`),
+		Action:  ActionMask,
+	})
+	return NewModerator(140, NewWordFilter(words), rules)
+}
+
+func TestModerateMasking(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "clean text",
+			text: "I had something interesting for breakfast",
+			want: "I had something interesting for breakfast",
+		},
+		{
+			name: "banned words in any case",
+			text: "I hear Mastodon is better than Chirpy. sharbert I need to migrate Kerfuffle",
+			want: "I hear Mastodon is better than Chirpy. **** I need to migrate ****",
+		},
+		{
+			name: "trailing punctuation",
+			text: "what a fornax!",
+			want: "what a ****!",
+		},
+		{
+			name: "leetspeak",
+			text: "such a k3rfuffl3, f0rn4x",
+			want: "such a ****, ****",
+		},
+		{
+			name: "leading symbol",
+			text: "hey @fornax",
+			want: "hey ****",
+		},
+		{
+			name: "substring is not a match",
+			text: "fornaxes are not fornax",
+			want: "fornaxes are not ****",
+		},
+		{
+			name: "regex rule",
+			text: "call me at 555-123-4567",
+			want: "call me at ************",
+		},
+	}
+
+	moderator := newTestModerator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := moderator.Moderate(tt.text)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Text != tt.want {
+				t.Errorf("got %q, want %q", got.Text, tt.want)
+			}
+			if got.Flagged {
+				t.Errorf("Expected text not to be flagged")
+			}
+		})
+	}
+}
+
+func TestModerateReject(t *testing.T) {
+	_, err := newTestModerator().Moderate("you are a SLUR")
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("got error %v, want *RejectedError", err)
+	}
+	if rejected.Rule != "word:slur" {
+		t.Errorf("got rule %q, want %q", rejected.Rule, "word:slur")
+	}
+}
+
+func TestModerateFlag(t *testing.T) {
+	got, err := newTestModerator().Moderate("this is not a scam, fornax")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Flagged {
+		t.Errorf("Expected text to be flagged")
+	}
+	if want := []string{"word:scam"}; !reflect.DeepEqual(got.Reasons, want) {
+		t.Errorf("got reasons %v, want %v", got.Reasons, want)
+	}
+	if want := "this is not a scam, ****"; got.Text != want {
+		t.Errorf("got %q, want %q", got.Text, want)
+	}
+}
+
+func TestModerateCountsRunes(t *testing.T) {
+	moderator := newTestModerator()
+
+	// 140 runes but 420 bytes
+	if _, err := moderator.Moderate(strings.Repeat("語", 140)); err != nil {
+		t.Errorf("Expected 140 runes to be accepted, got %v", err)
+	}
+	if _, err := moderator.Moderate(strings.Repeat("語", 141)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("got %v, want ErrTooLong", err)
+	}
+}
+
+func TestWordListUpdates(t *testing.T) {
+	list := NewWordList()
+	moderator := NewModerator(140, NewWordFilter(list))
+
+	list.Set("Blorp", ActionMask)
+	got, _ := moderator.Moderate("blorp blorp")
+	if got.Text != "**** ****" {
+		t.Errorf("got %q, want %q", got.Text, "**** ****")
+	}
+
+	list.Remove("BLORP")
+	got, _ = moderator.Moderate("blorp blorp")
+	if got.Text != "blorp blorp" {
+		t.Errorf("got %q, want %q", got.Text, "blorp blorp")
+	}
+}
+
+func TestParseWordList(t *testing.T) {
+	input := `# default word list
+kerfuffle
+
+sharbert mask
+scam flag
+slur reject
+`
+	got, err := ParseWordList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Word{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "sharbert", Action: ActionMask},
+		{Word: "scam", Action: ActionFlag},
+		{Word: "slur", Action: ActionReject},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := ParseWordList(strings.NewReader("word explode\n")); err == nil {
+		t.Errorf("Expected unknown action to fail")
+	}
+}
+
+func TestParseRegexRules(t *testing.T) {
+	input := `# name action pattern
+phone-number mask \b\d{3}-\d{3}-\d{4}\b
+crypto-spam flag free (btc|eth) giveaway
+`
+	rules, err := ParseRegexRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+	if rules[1].Name != "crypto-spam" || rules[1].Action != ActionFlag {
+		t.Errorf("got rule %q with action %q", rules[1].Name, rules[1].Action)
+	}
+	if !rules[1].Pattern.MatchString("join the free btc giveaway") {
+		t.Errorf("Expected pattern with spaces to be kept intact")
+	}
+
+	for _, bad := range []string{"missing-pattern mask", "bad-action explode x", "bad-regex mask ("} {
+		if _, err := ParseRegexRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected %q to fail", bad)
+		}
+	}
+}
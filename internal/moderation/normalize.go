@@ -0,0 +1,65 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Common character substitutions used to sneak words past filters
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+}
+
+// Normalizes a single word for comparison: lowercased with leetspeak
+// substitutions undone
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if sub, ok := leetspeak[r]; ok {
+			r = sub
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Runes that may be part of a word. '@' and '$' are included so leetspeak
+// spellings stay in one token; '!' is not, so "fornax!" splits cleanly.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '@' || r == '$'
+}
+
+type token struct {
+	// Byte offsets into the original text
+	start, end int
+	text       string
+}
+
+// Splits text into runs of word runes, dropping whitespace and punctuation
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{start: start, end: i, text: text[start:i]})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{start: start, end: len(text), text: text[start:]})
+	}
+	return tokens
+}
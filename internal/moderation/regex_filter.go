@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+type RegexRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Action  Action
+}
+
+type RegexFilter struct {
+	rules []RegexRule
+}
+
+func NewRegexFilter(rules ...RegexRule) *RegexFilter {
+	return &RegexFilter{rules: rules}
+}
+
+// Masked matches are replaced with one asterisk per rune
+func (f *RegexFilter) Apply(text string) (string, []Match) {
+	var matches []Match
+	for _, rule := range f.rules {
+		if !rule.Pattern.MatchString(text) {
+			continue
+		}
+		matches = append(matches, Match{Rule: "regex:" + rule.Name, Action: rule.Action})
+		if rule.Action == ActionMask {
+			text = rule.Pattern.ReplaceAllStringFunc(text, func(s string) string {
+				return strings.Repeat("*", utf8.RuneCountInString(s))
+			})
+		}
+	}
+	return text, matches
+}
+
+// Reads regex rules with one "<name> <action> <pattern>" per line. The
+// pattern is everything after the action, so it may contain spaces. Blank
+// lines and lines starting with # are ignored.
+func ParseRegexRules(r io.Reader) ([]RegexRule, error) {
+	var rules []RegexRule
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest, _ := strings.Cut(line, " ")
+		rawAction, pattern, found := strings.Cut(strings.TrimSpace(rest), " ")
+		if !found || strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("line %d: expected <name> <action> <pattern>", lineNo)
+		}
+		action, err := ParseAction(rawAction)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		compiled, err := regexp.Compile(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		rules = append(rules, RegexRule{Name: name, Pattern: compiled, Action: action})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func LoadRegexRulesFile(path string) ([]RegexRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRegexRules(f)
+}
@@ -0,0 +1,150 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const maskReplacement = "****"
+
+type Word struct {
+	Word   string
+	Action Action
+}
+
+// A set of banned words that is safe to modify while in use
+type WordList struct {
+	mu    sync.RWMutex
+	words map[string]Action
+}
+
+func NewWordList(words ...Word) *WordList {
+	l := &WordList{words: make(map[string]Action)}
+	for _, w := range words {
+		l.Set(w.Word, w.Action)
+	}
+	return l
+}
+
+// Reads a word list with one word per line, optionally followed by an
+// action. Blank lines and lines starting with # are ignored. Words
+// without an action are masked.
+func ParseWordList(r io.Reader) ([]Word, error) {
+	var words []Word
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		word := Word{Word: fields[0], Action: ActionMask}
+		if len(fields) > 1 {
+			action, err := ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			word.Action = action
+		}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+func LoadWordListFile(path string) ([]Word, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWordList(f)
+}
+
+func (l *WordList) Set(word string, action Action) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.words[Normalize(word)] = action
+}
+
+func (l *WordList) Remove(word string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.words, Normalize(word))
+}
+
+func (l *WordList) Lookup(word string) (Action, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	action, ok := l.words[Normalize(word)]
+	return action, ok
+}
+
+// Returns the normalized words sorted alphabetically
+func (l *WordList) Words() []Word {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	words := make([]Word, 0, len(l.words))
+	for w, action := range l.words {
+		words = append(words, Word{Word: w, Action: action})
+	}
+	sort.Slice(words, func(i, j int) bool { return words[i].Word < words[j].Word })
+	return words
+}
+
+type WordFilter struct {
+	list *WordList
+}
+
+func NewWordFilter(list *WordList) *WordFilter {
+	return &WordFilter{list: list}
+}
+
+func (f *WordFilter) Apply(text string) (string, []Match) {
+	var matches []Match
+	var b strings.Builder
+	last := 0
+	for _, tok := range tokenize(text) {
+		action, ok := f.lookup(tok.text)
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Rule: "word:" + Normalize(tok.text), Action: action})
+		if action != ActionMask {
+			continue
+		}
+		b.WriteString(text[last:tok.start])
+		b.WriteString(maskReplacement)
+		last = tok.end
+	}
+	if last == 0 {
+		return text, matches
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+// Also tries the token with leading and trailing symbols trimmed, so
+// "@fornax" is caught without treating every '@' as an 'a'
+func (f *WordFilter) lookup(word string) (Action, bool) {
+	if action, ok := f.list.Lookup(word); ok {
+		return action, true
+	}
+	trimmed := strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if trimmed == "" || trimmed == word {
+		return "", false
+	}
+	return f.list.Lookup(trimmed)
+}
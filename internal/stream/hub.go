@@ -0,0 +1,205 @@
+// Package stream is an in-process publish/subscribe hub for pushing live
+// events to connected clients. Recent events are kept in a bounded replay
+// buffer so clients that reconnect can resume where they left off.
+package stream
+
+import (
+	"slices"
+	"sync"
+)
+
+// Event IDs increase by one per published event and start over when the
+// process restarts
+type Event struct {
+	ID   uint64
+	Type string
+	// JSON-encoded payload
+	Data []byte
+	// Tags subscribers can filter on, e.g. "author:<id>" or "hashtag:go"
+	Topics []string
+}
+
+// Reports whether e has the given topic
+func (e Event) HasTopic(topic string) bool {
+	return slices.Contains(e.Topics, topic)
+}
+
+// Decides which events a subscriber receives. A nil Filter matches every
+// event.
+type Filter func(Event) bool
+
+// Matches events carrying at least one of topics
+func AnyTopic(topics ...string) Filter {
+	return func(e Event) bool {
+		for _, topic := range topics {
+			if e.HasTopic(topic) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Matches events carrying every one of topics
+func AllTopics(topics ...string) Filter {
+	return func(e Event) bool {
+		for _, topic := range topics {
+			if !e.HasTopic(topic) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Matches events carrying none of topics
+func NoTopic(topics ...string) Filter {
+	return func(e Event) bool {
+		for _, topic := range topics {
+			if e.HasTopic(topic) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Matches events every filter matches. Nil filters match everything.
+func All(filters ...Filter) Filter {
+	return func(e Event) bool {
+		for _, filter := range filters {
+			if filter != nil && !filter(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type Hub struct {
+	mu         sync.Mutex
+	lastID     uint64
+	replay     []Event
+	replaySize int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+}
+
+// Returns a hub remembering the last replaySize events. Each subscriber
+// may fall bufferSize events behind before it is dropped.
+func NewHub(replaySize, bufferSize int) *Hub {
+	return &Hub{
+		replaySize: replaySize,
+		bufferSize: bufferSize,
+		subs:       map[*Subscription]struct{}{},
+	}
+}
+
+// Sends an event to every matching subscriber without blocking. A
+// subscriber whose buffer is full is closed and marked as lagged so it can
+// reconnect and catch up from the replay buffer instead of stalling
+// publishers.
+func (h *Hub) Publish(eventType string, data []byte, topics ...string) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, Data: data, Topics: topics}
+	if h.replaySize > 0 {
+		if len(h.replay) == h.replaySize {
+			h.replay = h.replay[1:]
+		}
+		h.replay = append(h.replay, event)
+	}
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.lagged = true
+			h.remove(sub)
+		}
+	}
+	return event
+}
+
+// Registers a subscriber. Buffered events published after lastEventID
+// that match filter are returned for replay; pass 0 to skip replay.
+// Nothing is lost or duplicated between the replay and the live channel.
+func (h *Hub) Subscribe(filter Filter, lastEventID uint64) (*Subscription, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var missed []Event
+	// An ID from before a restart is ahead of the counter, replay nothing
+	if lastEventID > 0 && lastEventID <= h.lastID {
+		for _, event := range h.replay {
+			if event.ID > lastEventID && (filter == nil || filter(event)) {
+				missed = append(missed, event)
+			}
+		}
+	}
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan Event, h.bufferSize),
+	}
+	h.subs[sub] = struct{}{}
+	return sub, missed
+}
+
+// Closes every subscription, used when the server shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// Number of live subscriptions
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Must be called with h.mu held
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.events)
+}
+
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan Event
+	// Set under hub.mu when the subscriber was dropped for falling behind
+	lagged bool
+}
+
+// Delivers matching events. The channel is closed when the subscription
+// is closed, the hub shuts down or the subscriber falls too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Reports whether the subscription was dropped for falling behind
+func (s *Subscription) Lagged() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.lagged
+}
+
+// Unsubscribes. Safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
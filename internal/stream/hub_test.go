@@ -0,0 +1,129 @@
+package stream
+
+import (
+	"testing"
+)
+
+func eventIDs(events []Event) []uint64 {
+	ids := make([]uint64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func TestPublishFilters(t *testing.T) {
+	hub := NewHub(10, 10)
+	sub, _ := hub.Subscribe(AnyTopic("author:a"), 0)
+	defer sub.Close()
+
+	hub.Publish("chirp", nil, "author:b")
+	hub.Publish("chirp", nil, "author:a", "hashtag:go")
+
+	select {
+	case event := <-sub.Events():
+		if event.ID != 2 {
+			t.Errorf("got event %d, want 2", event.ID)
+		}
+	default:
+		t.Fatal("expected an event")
+	}
+	select {
+	case event := <-sub.Events():
+		t.Errorf("unexpected event %d", event.ID)
+	default:
+	}
+}
+
+func TestFilters(t *testing.T) {
+	event := Event{Topics: []string{"author:a", "hashtag:go"}}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "any topic", filter: AnyTopic("author:b", "hashtag:go"), want: true},
+		{name: "any topic without a match", filter: AnyTopic("author:b"), want: false},
+		{name: "all topics", filter: AllTopics("author:a", "hashtag:go"), want: true},
+		{name: "all topics missing one", filter: AllTopics("author:a", "hashtag:rust"), want: false},
+		{name: "no topic", filter: NoTopic("author:b"), want: true},
+		{name: "no topic with a match", filter: NoTopic("author:b", "author:a"), want: false},
+		{name: "all of nothing", filter: All(), want: true},
+		{name: "all with nil", filter: All(nil, AnyTopic("author:a")), want: true},
+		{name: "all with a miss", filter: All(AnyTopic("author:a"), NoTopic("hashtag:go")), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(event); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		filter      Filter
+		want        []uint64
+	}{
+		{name: "no replay", lastEventID: 0, want: []uint64{}},
+		{name: "resume", lastEventID: 3, want: []uint64{4, 5}},
+		{name: "older than the buffer", lastEventID: 1, want: []uint64{3, 4, 5}},
+		{name: "filtered", lastEventID: 2, filter: AnyTopic("odd"), want: []uint64{3, 5}},
+		{name: "from before a restart", lastEventID: 99, want: []uint64{}},
+	}
+
+	hub := NewHub(3, 10)
+	for i := 1; i <= 5; i++ {
+		topic := "even"
+		if i%2 == 1 {
+			topic = "odd"
+		}
+		hub.Publish("chirp", nil, topic)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, missed := hub.Subscribe(tt.filter, tt.lastEventID)
+			defer sub.Close()
+			got := eventIDs(missed)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub(10, 1)
+	slow, _ := hub.Subscribe(nil, 0)
+	fast, _ := hub.Subscribe(nil, 0)
+	defer fast.Close()
+
+	hub.Publish("chirp", nil)
+	<-fast.Events()
+	hub.Publish("chirp", nil)
+
+	if !slow.Lagged() {
+		t.Error("expected the slow subscriber to be marked as lagged")
+	}
+	if _, ok := <-slow.Events(); !ok {
+		t.Fatal("expected the buffered event before the channel closes")
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("expected the slow subscriber's channel to be closed")
+	}
+	if fast.Lagged() {
+		t.Error("fast subscriber should not be dropped")
+	}
+	if got := hub.Subscribers(); got != 1 {
+		t.Errorf("got %d subscribers, want 1", got)
+	}
+	slow.Close()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// Liking a chirp twice is not an error
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	chirp, err := cfg.pathChirp(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if chirp.UserID.Valid {
+		if err := cfg.ensureNotBlocked(r.Context(), userID, chirp.UserID.UUID); err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+	}
+	liked, err := cfg.queries.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("like_failed", "Failed to like chirp", err))
+		return
+	}
+	if liked > 0 && chirp.UserID.Valid {
+		cfg.notifier.enqueue(notificationJob{
+			Kind:       notificationLike,
+			ActorID:    userID,
+			ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Recipients: []uuid.UUID{chirp.UserID.UUID},
+		})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unliking a chirp that isn't liked is not an error
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	chirpID, err := cfg.pathChirpID(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	_, err = cfg.queries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("unlike_failed", "Failed to unlike chirp", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Resolves the {chirpID} path value to a chirp that exists and isn't deleted
+func (cfg *apiConfig) pathChirpID(r *http.Request) (uuid.UUID, error) {
+	chirp, err := cfg.pathChirp(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return chirp.ID, nil
+}
+
+func (cfg *apiConfig) pathChirp(r *http.Request) (database.Chirp, error) {
+	id, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		return database.Chirp{}, errChirpNotFound
+	}
+	chirp, err := cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		ViewerID: viewerFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Chirp{}, errChirpNotFound
+	}
+	if err != nil {
+		return database.Chirp{}, apierror.Internal("chirp_fetch_failed", "Chirp unable to be fetched", err)
+	}
+	return chirp, nil
+}
+
+// Fills in LikeCount and LikedByMe for every chirp with a single query
+func (cfg *apiConfig) attachLikes(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+	summaries, err := cfg.queries.GetLikeSummaries(ctx, database.GetLikeSummariesParams{
+		ViewerID: viewerFromContext(ctx),
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	byChirp := make(map[uuid.UUID]database.GetLikeSummariesRow, len(summaries))
+	for _, summary := range summaries {
+		byChirp[summary.ChirpID] = summary
+	}
+	for _, chirp := range chirps {
+		summary := byChirp[chirp.ID]
+		chirp.LikeCount = int(summary.LikeCount)
+		chirp.LikedByMe = summary.LikedByViewer
+	}
+	return nil
+}
+
+func chirpPointers(chirps []Chirp) []*Chirp {
+	ptrs := make([]*Chirp, len(chirps))
+	for i := range chirps {
+		ptrs[i] = &chirps[i]
+	}
+	return ptrs
+}
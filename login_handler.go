@@ -1,44 +1,105 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"log"
 	"net/http"
+	"time"
 
+	"chirpy.com/internal/apierror"
 	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
 )
 
+const accessTokenExpiry = time.Hour
+
 func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	type paramaters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	decoder := json.NewDecoder(r.Body)
 	params := paramaters{}
-	err := decoder.Decode(&params)
+	err := decodeJSONBody(r, &params)
 	if err != nil {
-		msg := fmt.Sprintf("Error marshalling JSON: %s", err)
-		cfg.respondWithError(w, 500, msg)
+		cfg.respondWithError(w, r, err)
+		return
+	}
+
+	wait, err := cfg.loginRetryAfter(r, params.Email)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("lockout_check_failed", "Failed to check login attempts", err))
+		return
+	}
+	if wait > 0 {
+		cfg.respondTooManyAttempts(w, r, wait)
 		return
 	}
 
 	dbUser, err := cfg.queries.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
-		cfg.respondWithError(w, 401, "Incorrect email or password")
+		cfg.recordLoginFailure(r, params.Email)
+		cfg.respondWithError(w, r, errInvalidCredentials)
 		return
 	}
 
 	err = auth.CheckPasswordHash(params.Password, dbUser.HashedPassword)
 	if err != nil {
-		cfg.respondWithError(w, 401, "Incorrect email or password")
+		cfg.recordLoginFailure(r, params.Email)
+		cfg.respondWithError(w, r, errInvalidCredentials)
+		return
+	}
+	cfg.recordLoginSuccess(r, params.Email)
+	// Only revealed once the password has been checked
+	if dbUser.SuspendedAt.Valid {
+		cfg.respondWithError(w, r, errAccountSuspended)
+		return
+	}
+
+	// Transparently upgrade hashes made with an older algorithm or weaker
+	// parameters while we have the plaintext password at hand
+	if auth.NeedsRehash(dbUser.HashedPassword) {
+		cfg.rehashPassword(r.Context(), dbUser.ID, params.Password)
+	}
+
+	token, err := auth.MakeJWT(dbUser.ID, auth.Role(dbUser.Role), cfg.jwtSecret, accessTokenExpiry)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("access_token_failed", "Failed to create access token", err))
+		return
+	}
+	// Each login starts a new refresh token family
+	refreshToken, err := cfg.issueRefreshToken(r.Context(), dbUser.ID, uuid.New())
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("refresh_token_failed", "Failed to create refresh token", err))
 		return
 	}
 
 	user := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
+		ID:           dbUser.ID,
+		CreatedAt:    dbUser.CreatedAt,
+		UpdatedAt:    dbUser.UpdatedAt,
+		Email:        dbUser.Email,
+		IsChirpyRed:  dbUser.IsChirpyRed,
+		Handle:       dbUser.Handle.String,
+		Token:        token,
+		RefreshToken: refreshToken,
 	}
 	cfg.respondWithJSON(w, 200, user)
 }
+
+// A failed upgrade is logged but never fails the login, the old hash
+// still verifies
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("Error rehashing password for user %s: %s", userID, err)
+		return
+	}
+	err = cfg.queries.UpdateUserPasswordHash(ctx, database.UpdateUserPasswordHashParams{
+		ID:             userID,
+		HashedPassword: hash,
+	})
+	if err != nil {
+		log.Printf("Error storing rehashed password for user %s: %s", userID, err)
+	}
+}
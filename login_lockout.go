@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/lockout"
+)
+
+var accountLockoutPolicy = lockout.Policy{
+	FreeAttempts: 5,
+	BaseDelay:    time.Second,
+	MaxDelay:     15 * time.Minute,
+	Window:       time.Hour,
+}
+
+// Many users can share an IP behind a NAT, so IPs get more free attempts
+var ipLockoutPolicy = lockout.Policy{
+	FreeAttempts: 20,
+	BaseDelay:    time.Second,
+	MaxDelay:     15 * time.Minute,
+	Window:       time.Hour,
+}
+
+func accountLockoutKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLockoutKey(ip string) string {
+	return "ip:" + ip
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Returns the longest remaining lock on either the account or the client IP
+func (cfg *apiConfig) loginRetryAfter(r *http.Request, email string) (time.Duration, error) {
+	accountWait, err := cfg.accountLimiter.RetryAfter(r.Context(), accountLockoutKey(email))
+	if err != nil {
+		return 0, err
+	}
+	ipWait, err := cfg.ipLimiter.RetryAfter(r.Context(), ipLockoutKey(clientIP(r)))
+	if err != nil {
+		return 0, err
+	}
+	return max(accountWait, ipWait), nil
+}
+
+func (cfg *apiConfig) recordLoginFailure(r *http.Request, email string) {
+	cfg.metrics.logins.Inc(loginFailed)
+	if err := cfg.accountLimiter.Fail(r.Context(), accountLockoutKey(email)); err != nil {
+		log.Printf("Error recording failed login for account: %s", err)
+	}
+	if err := cfg.ipLimiter.Fail(r.Context(), ipLockoutKey(clientIP(r))); err != nil {
+		log.Printf("Error recording failed login for IP: %s", err)
+	}
+}
+
+// The IP counter is deliberately left alone so one valid account can't be
+// used to reset an attacker's budget
+func (cfg *apiConfig) recordLoginSuccess(r *http.Request, email string) {
+	cfg.metrics.logins.Inc(loginSucceeded)
+	if err := cfg.accountLimiter.Reset(r.Context(), accountLockoutKey(email)); err != nil {
+		log.Printf("Error resetting failed logins for account: %s", err)
+	}
+}
+
+func (cfg *apiConfig) respondTooManyAttempts(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	cfg.respondWithError(w, r, apierror.RateLimited("login_locked", "Too many failed login attempts, try again later"))
+}
+
+// Clears the lock on an account and/or an IP address
+func (cfg *apiConfig) clearLockoutHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+		IP    string `json:"ip"`
+	}
+
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if params.Email == "" && params.IP == "" {
+		cfg.respondWithError(w, r, apierror.Validation("missing_lockout_key", "email or ip is required",
+			apierror.FieldError{Field: "email", Code: "required", Message: "email or ip is required"},
+			apierror.FieldError{Field: "ip", Code: "required", Message: "email or ip is required"},
+		))
+		return
+	}
+	if params.Email != "" {
+		if err := cfg.accountLimiter.Reset(r.Context(), accountLockoutKey(params.Email)); err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("lockout_clear_failed", "Failed to clear account lock", err))
+			return
+		}
+	}
+	if params.IP != "" {
+		if err := cfg.ipLimiter.Reset(r.Context(), ipLockoutKey(params.IP)); err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("lockout_clear_failed", "Failed to clear IP lock", err))
+			return
+		}
+	}
+	cfg.audit(r, auditLockoutClear, "lockout", "", map[string]any{"email": params.Email, "ip": params.IP})
+	w.WriteHeader(http.StatusNoContent)
+}
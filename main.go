@@ -1,33 +1,65 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
-	"sync/atomic"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
 	"chirpy.com/internal/database"
+	"chirpy.com/internal/lockout"
+	"chirpy.com/internal/moderation"
+	"chirpy.com/internal/stream"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	queries        *database.Queries
-	platform       string
+	db                 *sql.DB
+	queries            *database.Queries
+	platform           string
+	jwtSecret          string
+	polkaKey           string
+	polkaWebhookSecret string
+	metricsToken       string
+	metrics            *serverMetrics
+	accountLimiter     *lockout.Limiter
+	ipLimiter          *lockout.Limiter
+	bannedWords        *moderation.WordList
+	// The defaults or MODERATION_WORDS_FILE, before runtime changes
+	baseBannedWords  []moderation.Word
+	moderator        *moderation.Moderator
+	messageModerator *moderation.Moderator
+	chirpHub         *stream.Hub
+	notificationHub  *stream.Hub
+	notifier         *notifier
+	// Closed when the server starts shutting down
+	shutdown chan struct{}
+	// Hijacked WebSocket connections, which http.Server.Shutdown does not
+	// wait for
+	liveConns sync.WaitGroup
 }
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Email        string    `json:"email"`
+	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	Handle       string    `json:"handle,omitempty"`
+	Token        string    `json:"token,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
 }
 
 type Email struct {
@@ -40,58 +72,55 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
-		next.ServeHTTP(w, r)
-	})
-}
-
-func (cfg *apiConfig) formatHitsCount() string {
-	return fmt.Sprintf(
-		`<html>
-  <body>
-    <h1>Welcome, Chirpy Admin</h1>
-    <p>Chirpy has been visited %d times!</p>
-  </body>
-</html>`, cfg.fileserverHits.Load())
-}
-
-func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(200)
-	w.Write([]byte(cfg.formatHitsCount()))
-}
-
+// Deletes every user. The admin role decides who may reset; the platform
+// check stays on top of it because a reset wipes every account, which must
+// never be possible in production whoever asks.
 func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
 	if cfg.platform != "dev" {
-		w.WriteHeader(403)
+		cfg.respondWithError(w, r, errDevOnly)
 		return
 	}
 
+	// Recorded first: deleting the users clears the entry's actor
+	cfg.audit(r, auditReset, "platform", "", nil)
 	err := cfg.queries.DeleteAllUsers(r.Context())
 	if err != nil {
-		cfg.respondWithError(w, 500, "Failed to delete users")
+		cfg.respondWithError(w, r, apierror.Internal("reset_failed", "Failed to delete users", err))
 		return
 	}
 
-	cfg.fileserverHits.Store(0)
+	cfg.metrics.registry.Reset()
 	w.WriteHeader(200)
 }
 
-func (cfg *apiConfig) respondWithError(w http.ResponseWriter, code int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	respBody := ErrorResponse{
-		Error: msg,
+// Writes err as an application/problem+json document. Errors that are not
+// an *apierror.Error are reported as a generic 500. The cause of a 5xx is
+// never shown to the client, so it is logged under the request's ID.
+func (cfg *apiConfig) respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.Status()
 	}
-	data, err := json.Marshal(respBody)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
-		w.WriteHeader(500)
-		return
+	if status >= 500 {
+		slog.ErrorContext(r.Context(), "request failed",
+			slog.String("request_id", requestIDFromContext(r.Context())),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Any("error", err),
+		)
 	}
-	w.Write(data)
+	apierror.Write(w, r, err)
+}
+
+// Decodes the JSON request body into v, reporting syntax errors as a
+// malformed request rather than a server error
+func decodeJSONBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return apierror.Malformed(err)
+	}
+	return nil
 }
 
 func (cfg *apiConfig) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -106,25 +135,9 @@ func (cfg *apiConfig) respondWithJSON(w http.ResponseWriter, code int, payload i
 	w.Write(data)
 }
 
-func removeProfanity(msg string) string {
-	badWords := map[string]string{
-		"kerfuffle": "****",
-		"sharbert":  "****",
-		"fornax":    "****",
-	}
-	words := strings.Split(msg, " ")
-	for i, word := range words {
-		loweredWord := strings.ToLower(word)
-		if _, ok := badWords[loweredWord]; !ok {
-			continue
-		}
-		words[i] = strings.Replace(word, word, badWords[loweredWord], 1)
-	}
-	cleanedMsg := strings.Join(words, " ")
-	return cleanedMsg
-}
-
 func main() {
+	// Also routes the log package's output through the JSON handler
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
@@ -139,29 +152,155 @@ func main() {
 		log.Fatalf("Could not connect to the database: %v", err)
 	}
 	defer db.Close()
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
 	cfg := &apiConfig{
-		queries:  dbQueries,
-		platform: os.Getenv("PLATFORM"),
+		db:                 db,
+		queries:            dbQueries,
+		platform:           os.Getenv("PLATFORM"),
+		jwtSecret:          jwtSecret,
+		polkaKey:           os.Getenv("POLKA_KEY"),
+		polkaWebhookSecret: os.Getenv("POLKA_WEBHOOK_SECRET"),
+		metricsToken:       os.Getenv("METRICS_TOKEN"),
+		metrics:            newServerMetrics(db),
+		accountLimiter:     lockout.NewLimiter(lockout.NewMemoryStore(), accountLockoutPolicy),
+		ipLimiter:          lockout.NewLimiter(lockout.NewMemoryStore(), ipLockoutPolicy),
+		chirpHub:           stream.NewHub(chirpStreamReplaySize, chirpStreamBufferSize),
+		notificationHub:    stream.NewHub(notificationStreamReplaySize, notificationStreamBufferSize),
+		shutdown:           make(chan struct{}),
 	}
+	cfg.notifier = newNotifier(cfg.queries, cfg.notificationHub)
+	cfg.notifier.start(notificationWorkers)
+	bannedWords, err := cfg.loadBannedWords(context.Background())
+	if err != nil {
+		log.Fatalf("Could not load banned words: %v", err)
+	}
+	regexRules, err := loadRegexRules()
+	if err != nil {
+		log.Fatalf("Could not load moderation rules: %v", err)
+	}
+	cfg.bannedWords = bannedWords
+	cfg.moderator = moderation.NewModerator(maxChirpLength,
+		moderation.NewWordFilter(bannedWords),
+		moderation.NewRegexFilter(regexRules...),
+	)
+	// Messages follow the same content rules as chirps but may be longer
+	cfg.messageModerator = moderation.NewModerator(maxMessageLength,
+		moderation.NewWordFilter(bannedWords),
+		moderation.NewRegexFilter(regexRules...),
+	)
 	const filepathRoot = "."
 	const port = "8080"
 	mux := http.NewServeMux()
 	fileServer := http.StripPrefix("/app/", http.FileServer(http.Dir(filepathRoot)))
-	mux.Handle("/app/", cfg.middlewareMetricsInc(fileServer))
+	mux.Handle("/app/", fileServer)
 	srv := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: cfg.middlewareRequestLog(mux, cfg.middlewareMetrics(mux)),
 	}
 
+	srv.RegisterOnShutdown(cfg.closeLiveConnections)
+
 	mux.HandleFunc("GET /api/healthz", healthHandler)
+	mux.HandleFunc("GET /metrics", cfg.prometheusHandler)
 	mux.HandleFunc("POST /api/users", cfg.userHandler)
-	mux.HandleFunc("GET /admin/metrics", cfg.metricsHandler)
-	mux.HandleFunc("POST /admin/reset", cfg.resetHandler)
-	mux.HandleFunc("POST /api/chirps", cfg.chirpsHandler)
-	mux.HandleFunc("GET /api/chirps", cfg.getAllChirpsHandler)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirpHandler)
+	mux.HandleFunc("PUT /api/users", cfg.middlewareAuth(cfg.updateUserHandler))
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.middlewareAuth(cfg.followHandler))
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.middlewareAuth(cfg.unfollowHandler))
+	mux.HandleFunc("POST /api/users/{userID}/block", cfg.middlewareAuth(cfg.blockHandler))
+	mux.HandleFunc("DELETE /api/users/{userID}/block", cfg.middlewareAuth(cfg.unblockHandler))
+	mux.HandleFunc("POST /api/users/{userID}/mute", cfg.middlewareAuth(cfg.muteHandler))
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", cfg.middlewareAuth(cfg.unmuteHandler))
+	mux.HandleFunc("GET /api/blocks", cfg.middlewareAuth(cfg.listBlocksHandler))
+	mux.HandleFunc("GET /api/mutes", cfg.middlewareAuth(cfg.listMutesHandler))
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.listFollowersHandler)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.listFollowingHandler)
+	mux.HandleFunc("GET /api/timeline", cfg.middlewareAuth(cfg.timelineHandler))
+	mux.HandleFunc("GET /api/notifications", cfg.middlewareAuth(cfg.listNotificationsHandler))
+	mux.HandleFunc("POST /api/notifications/read", cfg.middlewareAuth(cfg.readNotificationsHandler))
+	mux.HandleFunc("POST /api/conversations", cfg.middlewareAuth(cfg.createConversationHandler))
+	mux.HandleFunc("GET /api/conversations", cfg.middlewareAuth(cfg.listConversationsHandler))
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", cfg.middlewareAuth(cfg.readConversationHandler))
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", cfg.middlewareAuth(cfg.createMessageHandler))
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", cfg.middlewareAuth(cfg.listMessagesHandler))
+	mux.HandleFunc("GET /admin/metrics", cfg.middlewareRole(auth.RoleAdmin, cfg.metricsHandler))
+	mux.HandleFunc("POST /admin/reset", cfg.middlewareRole(auth.RoleAdmin, cfg.resetHandler))
+	mux.HandleFunc("POST /admin/lockouts/clear", cfg.middlewareRole(auth.RoleAdmin, cfg.clearLockoutHandler))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", cfg.middlewareRole(auth.RoleAdmin, cfg.setUserRoleHandler))
+	mux.HandleFunc("GET /admin/audit", cfg.middlewareRole(auth.RoleAdmin, cfg.listAuditHandler))
+	mux.HandleFunc("GET /admin/moderation/words", cfg.middlewareRole(auth.RoleModerator, cfg.listBannedWordsHandler))
+	mux.HandleFunc("POST /admin/moderation/words", cfg.middlewareRole(auth.RoleModerator, cfg.putBannedWordHandler))
+	mux.HandleFunc("DELETE /admin/moderation/words/{word}", cfg.middlewareRole(auth.RoleModerator, cfg.deleteBannedWordHandler))
+	mux.HandleFunc("GET /admin/reports", cfg.middlewareRole(auth.RoleModerator, cfg.listReportsHandler))
+	mux.HandleFunc("POST /admin/reports/{reportID}/resolve", cfg.middlewareRole(auth.RoleModerator, cfg.resolveReportHandler))
+	mux.HandleFunc("POST /admin/chirps/{chirpID}/hide", cfg.middlewareRole(auth.RoleModerator, cfg.hideChirpHandler))
+	mux.HandleFunc("POST /admin/chirps/{chirpID}/restore", cfg.middlewareRole(auth.RoleModerator, cfg.restoreChirpHandler))
+	mux.HandleFunc("POST /admin/chirps/{chirpID}/undelete", cfg.middlewareRole(auth.RoleModerator, cfg.undeleteChirpHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/suspend", cfg.middlewareRole(auth.RoleModerator, cfg.suspendUserHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/unsuspend", cfg.middlewareRole(auth.RoleModerator, cfg.unsuspendUserHandler))
+	mux.HandleFunc("POST /api/chirps", cfg.middlewareAuth(cfg.chirpsHandler))
+	mux.HandleFunc("GET /api/chirps", cfg.middlewareOptionalAuth(cfg.getAllChirpsHandler))
+	mux.HandleFunc("GET /api/chirps/stream", cfg.middlewareOptionalAuth(cfg.chirpStreamHandler))
+	mux.HandleFunc("GET /api/ws", cfg.websocketHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.middlewareOptionalAuth(cfg.getChirpHandler))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.middlewareAuth(cfg.deleteChirpHandler))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.middlewareOptionalAuth(cfg.getThreadHandler))
+	mux.HandleFunc("GET /api/search", cfg.middlewareOptionalAuth(cfg.searchHandler))
+	mux.HandleFunc("GET /api/search/users", cfg.searchUsersHandler)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.middlewareOptionalAuth(cfg.hashtagChirpsHandler))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", cfg.middlewareAuth(cfg.rechirpHandler))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", cfg.middlewareAuth(cfg.unrechirpHandler))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", cfg.middlewareAuth(cfg.reportChirpHandler))
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/like", cfg.middlewareAuth(cfg.likeChirpHandler))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.middlewareAuth(cfg.unlikeChirpHandler))
 	mux.HandleFunc("POST /api/login", cfg.loginHandler)
+	mux.HandleFunc("POST /api/refresh", cfg.refreshHandler)
+	mux.HandleFunc("POST /api/revoke", cfg.revokeHandler)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.polkaWebhookHandler)
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(srv.ListenAndServe())
+	go func() {
+		slog.Info("serving", slog.String("root", filepathRoot), slog.String("port", port))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	slog.Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("shutdown failed", slog.Any("error", err))
+	}
+	// Handlers have returned, so no more notifications can be queued
+	cfg.notifier.stop()
+	cfg.waitForLiveConnections(ctx)
+}
+
+// How long in-flight requests and live connections get to finish
+const shutdownTimeout = 15 * time.Second
+
+// Tells streams and WebSockets to wind down. Registered with
+// http.Server.RegisterOnShutdown.
+func (cfg *apiConfig) closeLiveConnections() {
+	close(cfg.shutdown)
+	cfg.chirpHub.Close()
+	cfg.notificationHub.Close()
+}
+
+func (cfg *apiConfig) waitForLiveConnections(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		cfg.liveConns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Gave up waiting for live connections to close")
+	}
 }
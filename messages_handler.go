@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func messageFromDB(dbMessage database.Message) Message {
+	return Message{
+		ID:             dbMessage.ID,
+		CreatedAt:      dbMessage.CreatedAt,
+		ConversationID: dbMessage.ConversationID,
+		SenderID:       dbMessage.SenderID,
+		Body:           dbMessage.Body,
+	}
+}
+
+// Sends a message to a conversation the caller is a member of. The body is
+// moderated the same way as a chirp's.
+func (cfg *apiConfig) createMessageHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	conversation, err := cfg.pathConversation(r, userID)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	moderated, err := moderateBody(cfg.messageModerator, "message", maxMessageLength, params.Body)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	members, err := cfg.queries.GetConversationMembers(r.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("conversation_fetch_failed", "Failed to fetch conversation", err))
+		return
+	}
+	recipients := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		if member.UserID != userID {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+	// A block between the sender and anyone else in the conversation
+	// silences it for both of them
+	if err := cfg.ensureNotBlocked(r.Context(), userID, recipients...); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("message_create_failed", "Failed to send message", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	dbMessage, err := qtx.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           moderated.Text,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("message_create_failed", "Failed to send message", err))
+		return
+	}
+	// Keeps the conversation at the top of everyone's list
+	err = qtx.TouchConversation(r.Context(), database.TouchConversationParams{
+		ID:        conversation.ID,
+		UpdatedAt: dbMessage.CreatedAt,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("message_create_failed", "Failed to send message", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("message_create_failed", "Failed to send message", err))
+		return
+	}
+
+	message := messageFromDB(dbMessage)
+	cfg.publishMessage(message, recipients)
+	cfg.respondWithJSON(w, http.StatusCreated, message)
+}
+
+// Pushes a new message to the recipients' notification streams
+func (cfg *apiConfig) publishMessage(message Message, recipients []uuid.UUID) {
+	if len(recipients) == 0 {
+		return
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding message %s for streaming: %s", message.ID, err)
+		return
+	}
+	topics := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		topics[i] = recipientTopic(recipient)
+	}
+	cfg.notificationHub.Publish("message", data, topics...)
+}
+
+// Lists a conversation's messages, newest first. Paginated with ?limit= and
+// ?cursor= like GET /api/chirps.
+func (cfg *apiConfig) listMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	conversation, err := cfg.pathConversation(r, userID)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	dbMessages, err := cfg.queries.ListMessages(r.Context(), database.ListMessagesParams{
+		ConversationID:  conversation.ID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("messages_fetch_failed", "Failed to fetch messages", err))
+		return
+	}
+	if len(dbMessages) > limit {
+		dbMessages = dbMessages[:limit]
+		last := dbMessages[len(dbMessages)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+
+	messages := make([]Message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		messages[i] = messageFromDB(dbMessage)
+	}
+	cfg.respondWithJSON(w, http.StatusOK, messages)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/metrics"
+)
+
+// Label used for requests that matched no route, so unknown paths can't
+// blow up the number of series
+const unmatchedRoute = "unmatched"
+
+// Label used for request methods outside the standard set, which a client
+// could otherwise pick freely
+const otherMethod = "OTHER"
+
+type serverMetrics struct {
+	registry      *metrics.Registry
+	requests      *metrics.Counter
+	duration      *metrics.Histogram
+	inFlight      *metrics.Gauge
+	responseSize  *metrics.Histogram
+	chirpsCreated *metrics.Counter
+	logins        *metrics.Counter
+}
+
+func newServerMetrics(db *sql.DB) *serverMetrics {
+	registry := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: registry,
+		requests: registry.NewCounter("chirpy_http_requests_total",
+			"HTTP requests served, by method, route and status code.", "method", "route", "code"),
+		duration: registry.NewHistogram("chirpy_http_request_duration_seconds",
+			"Time taken to serve HTTP requests.", metrics.DefaultBuckets, "method", "route"),
+		inFlight: registry.NewGauge("chirpy_http_requests_in_flight",
+			"HTTP requests currently being served.", "method", "route"),
+		responseSize: registry.NewHistogram("chirpy_http_response_size_bytes",
+			"Size of HTTP response bodies.", metrics.ExponentialBuckets(100, 10, 6), "method", "route"),
+		chirpsCreated: registry.NewCounter("chirpy_chirps_created_total",
+			"Chirps posted."),
+		logins: registry.NewCounter("chirpy_logins_total",
+			"Login attempts that reached a password check, by result.", "result"),
+	}
+	m.logins.Add(0, loginSucceeded)
+	m.logins.Add(0, loginFailed)
+
+	stats := func(read func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return read(db.Stats()) }
+	}
+	registry.NewGaugeFunc("chirpy_db_max_open_connections", "Maximum number of open database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	registry.NewGaugeFunc("chirpy_db_open_connections", "Open database connections, in use or idle.",
+		stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	registry.NewGaugeFunc("chirpy_db_in_use_connections", "Database connections currently in use.",
+		stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	registry.NewGaugeFunc("chirpy_db_idle_connections", "Idle database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	registry.NewCounterFunc("chirpy_db_wait_count_total", "Times a query waited for a free database connection.",
+		stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	registry.NewCounterFunc("chirpy_db_wait_duration_seconds_total", "Time spent waiting for a free database connection.",
+		stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	registry.NewCounterFunc("chirpy_db_max_idle_closed_total", "Connections closed because of the idle connection limit.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	registry.NewCounterFunc("chirpy_db_max_idle_time_closed_total", "Connections closed because they were idle too long.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }))
+	registry.NewCounterFunc("chirpy_db_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+	return m
+}
+
+// Results of chirpy_logins_total
+const (
+	loginSucceeded = "success"
+	loginFailed    = "failure"
+)
+
+// Returns the pattern of the route r matches without its method, e.g.
+// /api/chirps/{chirpID}
+func routeLabel(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	return pattern
+}
+
+// Returns r's method if it is one of the standard HTTP methods and OTHER
+// if not
+func methodLabel(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return r.Method
+	}
+	return otherMethod
+}
+
+// Records the request metrics for everything served by mux
+func (cfg *apiConfig) middlewareMetrics(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, route := methodLabel(r), routeLabel(mux, r)
+		m := cfg.metrics
+		m.inFlight.Inc(method, route)
+		defer m.inFlight.Dec(method, route)
+
+		start := time.Now()
+		rec := newResponseRecorder(w)
+		mux.ServeHTTP(rec, r)
+
+		m.requests.Inc(method, route, strconv.Itoa(rec.Status()))
+		m.duration.Observe(time.Since(start).Seconds(), method, route)
+		m.responseSize.Observe(float64(rec.bytes), method, route)
+	})
+}
+
+// Serves the metrics to Prometheus. When METRICS_TOKEN is set the scraper
+// has to present it as a bearer token; otherwise only admins can read them.
+func (cfg *apiConfig) prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		cfg.metrics.registry.Handler().ServeHTTP(w, r)
+	}
+	if cfg.metricsToken == "" {
+		cfg.middlewareRole(auth.RoleAdmin, serve)(w, r)
+		return
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.metricsToken)) != 1 {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	serve(w, r)
+}
+
+var adminMetricsTemplate = template.Must(template.New("metrics").Parse(`<html>
+  <body>
+    <h1>Welcome, Chirpy Admin</h1>
+    <p>Chirpy has been visited {{.AppHits}} times!</p>
+    <table>
+      <tr><th>Metric</th><th>Labels</th><th>Value</th></tr>
+      {{- range .Families}}{{range .Samples}}
+      <tr><td>{{.Name}}</td><td>{{range $i, $l := .Labels}}{{if $i}}, {{end}}{{$l.Name}}={{$l.Value}}{{end}}</td><td>{{.Value}}</td></tr>
+      {{- end}}{{end}}
+    </table>
+  </body>
+</html>`))
+
+// Renders the same registry /metrics serves as an HTML page
+func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	families := cfg.metrics.registry.Gather()
+	appHits := 0.0
+	for _, f := range families {
+		if f.Name != "chirpy_http_requests_total" {
+			continue
+		}
+		for _, s := range f.Samples {
+			if s.Label("route") == "/app/" {
+				appHits += s.Value
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	err := adminMetricsTemplate.Execute(w, struct {
+		AppHits  float64
+		Families []metrics.Family
+	}{appHits, families})
+	if err != nil {
+		log.Printf("Error rendering metrics page: %s", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chirpy.com/internal/auth"
+)
+
+func TestMethodLabel(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{http.MethodGet, "GET"},
+		{http.MethodDelete, "DELETE"},
+		{"PROPFIND", "OTHER"},
+		{"X-RANDOM-1234", "OTHER"},
+		{"get", "OTHER"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/api/healthz", nil)
+		if got := methodLabel(r); got != tt.want {
+			t.Errorf("methodLabel(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestPrometheusHandlerWithoutTokenRequiresAdmin(t *testing.T) {
+	cfg, fake := newTestConfig(t)
+	cfg.metrics = newServerMetrics(cfg.db)
+	user := fake.addUser(auth.RoleUser, false)
+	admin := fake.addUser(auth.RoleAdmin, false)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"user", bearer(t, cfg, user), http.StatusForbidden},
+		{"admin", bearer(t, cfg, admin), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			cfg.prometheusHandler(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"chirpy.com/internal/moderation"
+)
+
+const (
+	maxChirpLength   = 140
+	maxMessageLength = 1000
+)
+
+// Used when MODERATION_WORDS_FILE is not set
+var defaultBannedWords = []moderation.Word{
+	{Word: "kerfuffle", Action: moderation.ActionMask},
+	{Word: "sharbert", Action: moderation.ActionMask},
+	{Word: "fornax", Action: moderation.ActionMask},
+}
+
+type BannedWord struct {
+	Word   string            `json:"word"`
+	Action moderation.Action `json:"action"`
+}
+
+// Builds the banned word list from the word list file (or the defaults)
+// followed by the words managed at runtime through the admin API, which
+// take precedence
+func (cfg *apiConfig) loadBannedWords(ctx context.Context) (*moderation.WordList, error) {
+	words := defaultBannedWords
+	if path := os.Getenv("MODERATION_WORDS_FILE"); path != "" {
+		fileWords, err := moderation.LoadWordListFile(path)
+		if err != nil {
+			return nil, err
+		}
+		words = fileWords
+	}
+	cfg.baseBannedWords = words
+	list := moderation.NewWordList(words...)
+
+	dbWords, err := cfg.queries.ListBannedWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range dbWords {
+		action, err := moderation.ParseAction(w.Action)
+		if err != nil {
+			return nil, err
+		}
+		list.Set(w.Word, action)
+	}
+	return list, nil
+}
+
+// Regex rules are optional and loaded from MODERATION_RULES_FILE
+func loadRegexRules() ([]moderation.RegexRule, error) {
+	path := os.Getenv("MODERATION_RULES_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return moderation.LoadRegexRulesFile(path)
+}
+
+func (cfg *apiConfig) listBannedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	words := cfg.bannedWords.Words()
+	resp := make([]BannedWord, len(words))
+	for i, word := range words {
+		resp[i] = BannedWord{Word: word.Word, Action: word.Action}
+	}
+	cfg.respondWithJSON(w, http.StatusOK, resp)
+}
+
+// Adds a banned word or changes the action of an existing one
+func (cfg *apiConfig) putBannedWordHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Word   string `json:"word"`
+		Action string `json:"action"`
+	}
+
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if params.Word == "" {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_banned_word", "word is required", apierror.FieldError{
+			Field:   "word",
+			Code:    "required",
+			Message: "must not be empty",
+		}))
+		return
+	}
+	action, err := moderation.ParseAction(params.Action)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_banned_word", "Invalid moderation action", apierror.FieldError{
+			Field:   "action",
+			Code:    "invalid",
+			Message: "must be one of mask, reject or flag",
+		}))
+		return
+	}
+
+	word := moderation.Normalize(params.Word)
+	_, err = cfg.queries.UpsertBannedWord(r.Context(), database.UpsertBannedWordParams{
+		Word:   word,
+		Action: string(action),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("banned_word_save_failed", "Failed to save banned word", err))
+		return
+	}
+	cfg.bannedWords.Set(word, action)
+	cfg.audit(r, auditBannedWordPut, "banned_word", word, map[string]any{"action": action})
+	cfg.respondWithJSON(w, http.StatusOK, BannedWord{Word: word, Action: action})
+}
+
+func (cfg *apiConfig) deleteBannedWordHandler(w http.ResponseWriter, r *http.Request) {
+	word := moderation.Normalize(r.PathValue("word"))
+	if _, ok := cfg.bannedWords.Lookup(word); !ok {
+		cfg.respondWithError(w, r, apierror.NotFound("banned_word_not_found", "Word is not banned"))
+		return
+	}
+	deleted, err := cfg.queries.DeleteBannedWord(r.Context(), word)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("banned_word_delete_failed", "Failed to delete banned word", err))
+		return
+	}
+	// Words from the defaults or MODERATION_WORDS_FILE would come back on
+	// the next restart
+	if deleted == 0 {
+		cfg.respondWithError(w, r, apierror.Conflict("banned_word_not_removable", "Word comes from the built-in or configured word list and cannot be removed through the API"))
+		return
+	}
+	// A word that was only overriding the word list falls back to it
+	if base, ok := findBannedWord(cfg.baseBannedWords, word); ok {
+		cfg.bannedWords.Set(word, base.Action)
+	} else {
+		cfg.bannedWords.Remove(word)
+	}
+	cfg.audit(r, auditBannedWordDelete, "banned_word", word, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func findBannedWord(words []moderation.Word, word string) (moderation.Word, bool) {
+	for _, w := range words {
+		if moderation.Normalize(w.Word) == word {
+			return w, true
+		}
+	}
+	return moderation.Word{}, false
+}
+
+// Runs a body of text through a moderator, reporting verdicts as API
+// errors whose codes are prefixed with what is being posted, e.g.
+// chirp_too_long or message_rejected
+func moderateBody(moderator *moderation.Moderator, subject string, maxLength int, body string) (moderation.Result, error) {
+	title := strings.ToUpper(subject[:1]) + subject[1:]
+	moderated, err := moderator.Moderate(body)
+	if errors.Is(err, moderation.ErrTooLong) {
+		return moderation.Result{}, apierror.Validation(subject+"_too_long", title+" is too long", apierror.FieldError{
+			Field:   "body",
+			Code:    "too_long",
+			Message: fmt.Sprintf("must be at most %d characters", maxLength),
+		})
+	}
+	var rejected *moderation.RejectedError
+	if errors.As(err, &rejected) {
+		return moderation.Result{}, apierror.Validation(subject+"_rejected", title+" violates the content policy", apierror.FieldError{
+			Field:   "body",
+			Code:    "content_policy",
+			Message: "contains content that is not allowed",
+		})
+	}
+	if err != nil {
+		return moderation.Result{}, apierror.Internal("moderation_failed", "Failed to moderate "+subject, err)
+	}
+	return moderated, nil
+}
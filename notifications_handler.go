@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// One of mention, reply, like or follow
+	Type    string     `json:"type"`
+	ActorID uuid.UUID  `json:"actor_id"`
+	ChirpID *uuid.UUID `json:"chirp_id,omitempty"`
+	Read    bool       `json:"read"`
+}
+
+type NotificationsResponse struct {
+	UnreadCount   int64          `json:"unread_count"`
+	Notifications []Notification `json:"notifications"`
+}
+
+func notificationFromDB(dbNotification database.Notification) Notification {
+	notification := Notification{
+		ID:        dbNotification.ID,
+		CreatedAt: dbNotification.CreatedAt,
+		Type:      dbNotification.Kind,
+		ActorID:   dbNotification.ActorID,
+		Read:      dbNotification.ReadAt.Valid,
+	}
+	if dbNotification.ChirpID.Valid {
+		notification.ChirpID = &dbNotification.ChirpID.UUID
+	}
+	return notification
+}
+
+// Lists the caller's notifications, newest first, along with how many are
+// unread. Paginated with ?limit= and ?cursor= like GET /api/chirps.
+func (cfg *apiConfig) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	dbNotifications, err := cfg.queries.ListNotifications(r.Context(), database.ListNotificationsParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("notifications_fetch_failed", "Failed to fetch notifications", err))
+		return
+	}
+	unread, err := cfg.queries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("notifications_fetch_failed", "Failed to fetch notifications", err))
+		return
+	}
+	if len(dbNotifications) > limit {
+		dbNotifications = dbNotifications[:limit]
+		last := dbNotifications[len(dbNotifications)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+
+	response := NotificationsResponse{
+		UnreadCount:   unread,
+		Notifications: make([]Notification, len(dbNotifications)),
+	}
+	for i, dbNotification := range dbNotifications {
+		response.Notifications[i] = notificationFromDB(dbNotification)
+	}
+	cfg.respondWithJSON(w, http.StatusOK, response)
+}
+
+// Marks the caller's notifications as read up to and including up_to_id,
+// or all of them when it is omitted
+func (cfg *apiConfig) readNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		UpToID *uuid.UUID `json:"up_to_id"`
+	}
+
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	// An empty body marks everything read
+	params := parameters{}
+	if r.ContentLength != 0 {
+		if err := decodeJSONBody(r, &params); err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+	}
+
+	arg := database.MarkNotificationsReadParams{UserID: userID}
+	if params.UpToID != nil {
+		upTo, err := cfg.queries.GetNotification(r.Context(), database.GetNotificationParams{
+			ID:     *params.UpToID,
+			UserID: userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			cfg.respondWithError(w, r, apierror.Validation("notification_not_found", "The notification does not exist", apierror.FieldError{
+				Field:   "up_to_id",
+				Code:    "not_found",
+				Message: "must reference one of your notifications",
+			}))
+			return
+		}
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("notifications_fetch_failed", "Failed to fetch notifications", err))
+			return
+		}
+		arg.UpToCreatedAt = sql.NullTime{Time: upTo.CreatedAt, Valid: true}
+		arg.UpToID = uuid.NullUUID{UUID: upTo.ID, Valid: true}
+	}
+	if _, err := cfg.queries.MarkNotificationsRead(r.Context(), arg); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("notifications_update_failed", "Failed to mark notifications read", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
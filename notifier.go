@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"chirpy.com/internal/database"
+	"chirpy.com/internal/stream"
+	"github.com/google/uuid"
+)
+
+// Kinds of notification
+const (
+	notificationMention = "mention"
+	notificationReply   = "reply"
+	notificationLike    = "like"
+	notificationFollow  = "follow"
+)
+
+const (
+	notificationQueueSize  = 1024
+	notificationWorkers    = 4
+	notificationJobTimeout = 10 * time.Second
+)
+
+// One interaction to tell recipients about. Handlers queue a job and
+// return straight away; the notifier's workers store the notifications and
+// push them to connected clients.
+type notificationJob struct {
+	Kind       string
+	ActorID    uuid.UUID
+	ChirpID    uuid.NullUUID
+	Recipients []uuid.UUID
+}
+
+type notifier struct {
+	queries *database.Queries
+	hub     *stream.Hub
+	jobs    chan notificationJob
+	wg      sync.WaitGroup
+	// Guards jobs against sends after it is closed
+	mu      sync.Mutex
+	stopped bool
+}
+
+func newNotifier(queries *database.Queries, hub *stream.Hub) *notifier {
+	return &notifier{
+		queries: queries,
+		hub:     hub,
+		jobs:    make(chan notificationJob, notificationQueueSize),
+	}
+}
+
+func (n *notifier) start(workers int) {
+	for range workers {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			for job := range n.jobs {
+				n.process(job)
+			}
+		}()
+	}
+}
+
+// Finishes the queued jobs. Jobs enqueued afterwards, e.g. by handlers
+// still running when shutdown timed out, are dropped.
+func (n *notifier) stop() {
+	n.mu.Lock()
+	if !n.stopped {
+		n.stopped = true
+		close(n.jobs)
+	}
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+// Queues a job without blocking. Users never notify themselves. When the
+// queue is full the job is dropped rather than slowing down the request.
+func (n *notifier) enqueue(job notificationJob) {
+	recipients := make([]uuid.UUID, 0, len(job.Recipients))
+	seen := map[uuid.UUID]bool{job.ActorID: true}
+	for _, recipient := range job.Recipients {
+		if !seen[recipient] {
+			seen[recipient] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	job.Recipients = recipients
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		log.Printf("Notifier stopped, dropping %s notification from %s", job.Kind, job.ActorID)
+		return
+	}
+	select {
+	case n.jobs <- job:
+	default:
+		log.Printf("Notification queue full, dropping %s notification from %s", job.Kind, job.ActorID)
+	}
+}
+
+func (n *notifier) process(job notificationJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationJobTimeout)
+	defer cancel()
+	created, err := n.queries.CreateNotifications(ctx, database.CreateNotificationsParams{
+		ActorID:      job.ActorID,
+		Kind:         job.Kind,
+		ChirpID:      job.ChirpID,
+		RecipientIds: job.Recipients,
+	})
+	if err != nil {
+		log.Printf("Error creating %s notifications from %s: %s", job.Kind, job.ActorID, err)
+		return
+	}
+	for _, dbNotification := range created {
+		data, err := json.Marshal(notificationFromDB(dbNotification))
+		if err != nil {
+			log.Printf("Error encoding notification %s: %s", dbNotification.ID, err)
+			continue
+		}
+		n.hub.Publish("notification", data, recipientTopic(dbNotification.UserID))
+	}
+}
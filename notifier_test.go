@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNotifierDropsJobsAfterStop(t *testing.T) {
+	n := newNotifier(nil, nil)
+	n.start(0)
+	n.stop()
+
+	// Must not panic with a send on the closed queue
+	n.enqueue(notificationJob{
+		Kind:       notificationLike,
+		ActorID:    uuid.New(),
+		Recipients: []uuid.UUID{uuid.New()},
+	})
+	n.stop()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// Position of the last item on a page, used for keyset pagination on
+// (created_at, id)
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Cursors are opaque to clients; the encoding may change at any time
+func encodeCursor(c pageCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	return pageCursor{CreatedAt: t, ID: parsedID}, nil
+}
+
+// Reads ?cursor= into the nullable keyset parameters used by the list
+// queries; both are NULL when no cursor was given
+func parseCursorParam(query url.Values) (sql.NullTime, uuid.NullUUID, error) {
+	raw := query.Get("cursor")
+	if raw == "" {
+		return sql.NullTime{}, uuid.NullUUID{}, nil
+	}
+	cursor, err := decodeCursor(raw)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, err
+	}
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: cursor.ID, Valid: true}, nil
+}
+
+// Reads ?limit= falling back to the default and rejecting out of range values
+func parseLimit(query url.Values) (int, error) {
+	raw := query.Get("limit")
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, fmt.Errorf("must be between 1 and %d", maxPageLimit)
+	}
+	return limit, nil
+}
+
+func invalidQueryParam(name, message string) *apierror.Error {
+	return apierror.Validation("invalid_query_parameter", "Invalid query parameter "+name, apierror.FieldError{
+		Field:   name,
+		Code:    "invalid",
+		Message: message,
+	})
+}
+
+// Builds an RFC 8288 Link header value pointing at the next page
+func nextPageLink(u *url.URL, cursor string) string {
+	query := u.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="next"`, next.String())
+}
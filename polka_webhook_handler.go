@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+const polkaSignatureHeader = "X-Polka-Signature"
+
+type PolkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID uuid.UUID `json:"user_id"`
+	} `json:"data"`
+}
+
+// Reports whether the webhook request carries either the configured API key
+// or a valid HMAC signature of its body
+func (cfg *apiConfig) polkaRequestAuthorized(r *http.Request, body []byte) bool {
+	if key, err := auth.GetAPIKey(r.Header); err == nil && cfg.polkaKey != "" {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(cfg.polkaKey)) == 1 {
+			return true
+		}
+	}
+	if signature := r.Header.Get(polkaSignatureHeader); signature != "" {
+		return auth.VerifySignature(body, cfg.polkaWebhookSecret, signature) == nil
+	}
+	return false
+}
+
+// Receives payment events from Polka. Only user.upgraded is acted upon;
+// every other event is acknowledged and ignored. Events carrying an ID are
+// processed at most once.
+func (cfg *apiConfig) polkaWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.New(apierror.KindMalformed, "unreadable_body", "Failed to read request body"))
+		return
+	}
+	if !cfg.polkaRequestAuthorized(r, body) {
+		cfg.respondWithError(w, r, apierror.Unauthorized("invalid_webhook_credentials", "Missing or invalid API key or signature"))
+		return
+	}
+
+	event := PolkaEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		cfg.respondWithError(w, r, apierror.Malformed(err))
+		return
+	}
+	if event.Event != "user.upgraded" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("webhook_failed", "Failed to process event", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+
+	if event.ID != "" {
+		recorded, err := qtx.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+			ID:    event.ID,
+			Event: event.Event,
+		})
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("webhook_failed", "Failed to process event", err))
+			return
+		}
+		// Already processed, acknowledge the redelivery
+		if recorded == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	upgraded, err := qtx.UpgradeUserToChirpyRed(r.Context(), event.Data.UserID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_upgrade_failed", "Failed to upgrade user", err))
+		return
+	}
+	if upgraded == 0 {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("webhook_failed", "Failed to process event", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// Reposts {chirpID} without a body of its own. Rechirping a rechirp
+// reposts the original.
+func (cfg *apiConfig) rechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	original, err := cfg.pathChirp(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if original.RechirpOfID.Valid {
+		original, err = cfg.queries.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       original.RechirpOfID.UUID,
+			ViewerID: viewerFromContext(r.Context()),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			cfg.respondWithError(w, r, errChirpNotFound)
+			return
+		}
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("chirp_fetch_failed", "Chirp unable to be fetched", err))
+			return
+		}
+	}
+	if original.UserID.Valid {
+		if err := cfg.ensureNotBlocked(r.Context(), userID, original.UserID.UUID); err != nil {
+			cfg.respondWithError(w, r, err)
+			return
+		}
+	}
+
+	chirpID := uuid.New()
+	chirp, err := cfg.queries.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:        chirpID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Body:      "",
+		UserID: uuid.NullUUID{
+			UUID:  userID,
+			Valid: true,
+		},
+		ThreadRootID: chirpID,
+		RechirpOfID:  uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if isUniqueViolation(err) {
+		cfg.respondWithError(w, r, apierror.Conflict("already_rechirped", "You have already rechirped this chirp"))
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("rechirp_failed", "Failed to rechirp", err))
+		return
+	}
+
+	chirpResponse := chirpFromDB(chirp)
+	if err := cfg.hydrateChirps(r.Context(), []*Chirp{&chirpResponse}); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
+	}
+	cfg.publishChirp(chirpResponse)
+	cfg.respondWithJSON(w, http.StatusCreated, chirpResponse)
+}
+
+// Removes the caller's rechirp of {chirpID}
+func (cfg *apiConfig) unrechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	originalID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	rechirp, err := cfg.queries.GetRechirpByUser(r.Context(), database.GetRechirpByUserParams{
+		UserID:      uuid.NullUUID{UUID: userID, Valid: true},
+		RechirpOfID: uuid.NullUUID{UUID: originalID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, apierror.NotFound("rechirp_not_found", "You have not rechirped this chirp"))
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("rechirp_fetch_failed", "Rechirp unable to be fetched", err))
+		return
+	}
+	if err := cfg.queries.SoftDeleteChirp(r.Context(), rechirp.ID); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_delete_failed", "Chirp unable to be deleted", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) attachRechirpCounts(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+	counts, err := cfg.queries.GetRechirpCounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byChirp := make(map[uuid.UUID]database.GetRechirpCountsRow, len(counts))
+	for _, count := range counts {
+		byChirp[count.ChirpID] = count
+	}
+	for _, chirp := range chirps {
+		count := byChirp[chirp.ID]
+		chirp.RechirpCount = int(count.RechirpCount)
+		chirp.QuoteCount = int(count.QuoteCount)
+	}
+	return nil
+}
+
+// Loads the originals of rechirps and quotes. Originals that have since
+// been deleted or withheld by a moderator are reduced to a tombstone
+// carrying only their ID.
+func (cfg *apiConfig) attachEmbeddedChirps(ctx context.Context, chirps []*Chirp) error {
+	var embeds []*EmbeddedChirp
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			embeds = append(embeds, chirp.RechirpOf)
+		}
+		if chirp.QuoteOf != nil {
+			embeds = append(embeds, chirp.QuoteOf)
+		}
+	}
+	if len(embeds) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(embeds))
+	for i, embed := range embeds {
+		ids[i] = embed.ID
+	}
+	originals, err := cfg.queries.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]database.Chirp, len(originals))
+	for _, original := range originals {
+		byID[original.ID] = original
+	}
+	for _, embed := range embeds {
+		original, ok := byID[embed.ID]
+		if !ok || original.DeletedAt.Valid {
+			*embed = EmbeddedChirp{ID: embed.ID, Deleted: true}
+			continue
+		}
+		*embed = EmbeddedChirp{
+			ID:        original.ID,
+			CreatedAt: &original.CreatedAt,
+			Body:      original.Body,
+			UserID:    &original.UserID.UUID,
+		}
+	}
+	return nil
+}
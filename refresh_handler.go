@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+const refreshTokenExpiry = 60 * 24 * time.Hour
+
+// Creates and stores a new refresh token belonging to the given token family
+func (cfg *apiConfig) issueRefreshToken(ctx context.Context, userID, familyID uuid.UUID) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = cfg.queries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     token,
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Exchanges a refresh token for a new access token. The presented refresh
+// token is rotated: it is revoked and replaced by a new one in the same
+// family. Presenting an already revoked token is treated as reuse of a
+// stolen token and revokes the whole family.
+func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	presented, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		cfg.respondWithError(w, r, errMissingRefreshToken)
+		return
+	}
+
+	dbToken, err := cfg.queries.GetRefreshToken(r.Context(), presented)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, apierror.Unauthorized("invalid_refresh_token", "Invalid refresh token"))
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("refresh_token_fetch_failed", "Failed to fetch refresh token", err))
+		return
+	}
+
+	if dbToken.RevokedAt.Valid {
+		cfg.revokeTokenFamily(w, r, dbToken.FamilyID)
+		return
+	}
+	if time.Now().UTC().After(dbToken.ExpiresAt) {
+		cfg.respondWithError(w, r, apierror.Unauthorized("refresh_token_expired", "Refresh token expired"))
+		return
+	}
+
+	// Only one concurrent request may rotate a given token
+	revoked, err := cfg.queries.RevokeRefreshToken(r.Context(), presented)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("refresh_token_rotate_failed", "Failed to rotate refresh token", err))
+		return
+	}
+	if revoked == 0 {
+		cfg.revokeTokenFamily(w, r, dbToken.FamilyID)
+		return
+	}
+
+	refreshToken, err := cfg.issueRefreshToken(r.Context(), dbToken.UserID, dbToken.FamilyID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("refresh_token_failed", "Failed to create refresh token", err))
+		return
+	}
+	// The role is read again so promotions and demotions reach the next
+	// access token
+	dbUser, err := cfg.queries.GetUserByID(r.Context(), dbToken.UserID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_fetch_failed", "Failed to fetch user", err))
+		return
+	}
+	accessToken, err := auth.MakeJWT(dbToken.UserID, auth.Role(dbUser.Role), cfg.jwtSecret, accessTokenExpiry)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("access_token_failed", "Failed to create access token", err))
+		return
+	}
+
+	cfg.respondWithJSON(w, http.StatusOK, TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+func (cfg *apiConfig) revokeTokenFamily(w http.ResponseWriter, r *http.Request, familyID uuid.UUID) {
+	if err := cfg.queries.RevokeRefreshTokenFamily(r.Context(), familyID); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("refresh_token_revoke_failed", "Failed to revoke refresh tokens", err))
+		return
+	}
+	cfg.respondWithError(w, r, apierror.Unauthorized("refresh_token_revoked", "Refresh token has been revoked"))
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxReportDetailsLength = 500
+
+// Categories a chirp can be reported under
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "self_harm", "misinformation", "other"}
+
+// What a moderator decided to do about a reported chirp
+const (
+	decisionDismiss     = "dismiss"
+	decisionHideChirp   = "hide_chirp"
+	decisionSuspendUser = "suspend_user"
+)
+
+const (
+	reportStatusOpen     = "open"
+	reportStatusResolved = "resolved"
+)
+
+// Reason of the reports filed when the word filter flags a chirp. They
+// have no reporter and list the rules that matched in their details.
+const reportReasonFlagged = "flagged"
+
+var (
+	errReportNotFound = apierror.NotFound("report_not_found", "Report not found")
+	errTargetOutranks = apierror.Forbidden("target_outranks_caller", "You cannot act against a user whose role is equal to or higher than yours")
+)
+
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	ReporterID *uuid.UUID `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details,omitempty"`
+	// open or resolved
+	Status        string     `json:"status"`
+	Decision      string     `json:"decision,omitempty"`
+	ModeratorNote string     `json:"moderator_note,omitempty"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+// A report in the moderation queue along with the chirp it is about
+type ReportQueueEntry struct {
+	Report
+	Chirp ReportedChirp `json:"chirp"`
+}
+
+type ReportedChirp struct {
+	Body   string    `json:"body"`
+	UserID uuid.UUID `json:"user_id"`
+	Hidden bool      `json:"hidden"`
+}
+
+func reportFromDB(dbReport database.Report) Report {
+	report := Report{
+		ID:            dbReport.ID,
+		CreatedAt:     dbReport.CreatedAt,
+		ChirpID:       dbReport.ChirpID,
+		Reason:        dbReport.Reason,
+		Details:       dbReport.Details,
+		Status:        dbReport.Status,
+		Decision:      dbReport.Decision.String,
+		ModeratorNote: dbReport.ModeratorNote,
+	}
+	if dbReport.ReporterID.Valid {
+		report.ReporterID = &dbReport.ReporterID.UUID
+	}
+	if dbReport.ResolvedAt.Valid {
+		report.ResolvedAt = &dbReport.ResolvedAt.Time
+	}
+	return report
+}
+
+func validReportReason(reason string) bool {
+	for _, r := range reportReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports {chirpID} to the moderators. Each user may report a chirp once.
+func (cfg *apiConfig) reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	chirp, err := cfg.pathChirp(r)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if !validReportReason(params.Reason) {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_report", "Invalid report", apierror.FieldError{
+			Field:   "reason",
+			Code:    "invalid",
+			Message: "must be one of " + strings.Join(reportReasons, ", "),
+		}))
+		return
+	}
+	details := strings.TrimSpace(params.Details)
+	if utf8.RuneCountInString(details) > maxReportDetailsLength {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_report", "Invalid report", apierror.FieldError{
+			Field:   "details",
+			Code:    "too_long",
+			Message: fmt.Sprintf("must be at most %d characters", maxReportDetailsLength),
+		}))
+		return
+	}
+	if chirp.UserID.Valid && chirp.UserID.UUID == userID {
+		cfg.respondWithError(w, r, apierror.Validation("cannot_report_own_chirp", "You cannot report your own chirp"))
+		return
+	}
+
+	dbReport, err := cfg.queries.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:     params.Reason,
+		Details:    details,
+	})
+	if isUniqueViolation(err) {
+		cfg.respondWithError(w, r, apierror.Conflict("already_reported", "You have already reported this chirp"))
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_failed", "Failed to report chirp", err))
+		return
+	}
+	cfg.respondWithJSON(w, http.StatusCreated, reportFromDB(dbReport))
+}
+
+// Lists reports oldest first so the queue is worked in order. ?status=
+// selects open (the default) or resolved reports. Paginated with ?limit=
+// and ?cursor=.
+func (cfg *apiConfig) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status == "" {
+		status = reportStatusOpen
+	}
+	if status != reportStatusOpen && status != reportStatusResolved {
+		cfg.respondWithError(w, r, invalidQueryParam("status", "must be open or resolved"))
+		return
+	}
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	rows, err := cfg.queries.ListReports(r.Context(), database.ListReportsParams{
+		Status:          status,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("reports_fetch_failed", "Failed to fetch reports", err))
+		return
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+
+	entries := make([]ReportQueueEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = ReportQueueEntry{
+			Report: reportFromDB(database.Report{
+				ID:            row.ID,
+				CreatedAt:     row.CreatedAt,
+				ChirpID:       row.ChirpID,
+				ReporterID:    row.ReporterID,
+				Reason:        row.Reason,
+				Details:       row.Details,
+				Status:        row.Status,
+				Decision:      row.Decision,
+				ModeratorNote: row.ModeratorNote,
+				ResolvedAt:    row.ResolvedAt,
+			}),
+			Chirp: ReportedChirp{
+				Body:   row.ChirpBody,
+				UserID: row.ChirpUserID.UUID,
+				Hidden: row.ChirpHiddenAt.Valid,
+			},
+		}
+	}
+	cfg.respondWithJSON(w, http.StatusOK, entries)
+}
+
+// Records a moderator's decision on {reportID} and carries it out. The
+// decision answers every open report about the same chirp.
+func (cfg *apiConfig) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Decision string `json:"decision"`
+		Note     string `json:"note"`
+	}
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errReportNotFound)
+		return
+	}
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	switch params.Decision {
+	case decisionDismiss, decisionHideChirp, decisionSuspendUser:
+	default:
+		cfg.respondWithError(w, r, apierror.Validation("invalid_decision", "Invalid moderation decision", apierror.FieldError{
+			Field:   "decision",
+			Code:    "invalid",
+			Message: "must be one of dismiss, hide_chirp or suspend_user",
+		}))
+		return
+	}
+
+	report, err := cfg.queries.GetReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, errReportNotFound)
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_fetch_failed", "Failed to fetch report", err))
+		return
+	}
+	if report.Status != reportStatusOpen {
+		cfg.respondWithError(w, r, apierror.Conflict("report_already_resolved", "The report has already been resolved"))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	switch params.Decision {
+	case decisionHideChirp:
+		if _, err := qtx.HideChirp(r.Context(), report.ChirpID); err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+			return
+		}
+	case decisionSuspendUser:
+		authorID, err := qtx.GetChirpAuthorID(r.Context(), report.ChirpID)
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+			return
+		}
+		if authorID.Valid {
+			if err := ensureOutranks(r, qtx, authorID.UUID); err != nil {
+				cfg.respondWithError(w, r, err)
+				return
+			}
+			if _, err := suspendUser(r.Context(), qtx, authorID.UUID); err != nil {
+				cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+				return
+			}
+		}
+	}
+	_, err = qtx.ResolveReportsForChirp(r.Context(), database.ResolveReportsForChirpParams{
+		Decision:      sql.NullString{String: params.Decision, Valid: true},
+		ModeratorNote: strings.TrimSpace(params.Note),
+		ChirpID:       report.ChirpID,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+		return
+	}
+	err = recordAudit(r, qtx, auditReportResolve, "report", report.ID.String(), map[string]any{
+		"chirp_id": report.ChirpID,
+		"decision": params.Decision,
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_resolve_failed", "Failed to resolve report", err))
+		return
+	}
+
+	resolved, err := cfg.queries.GetReport(r.Context(), report.ID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("report_fetch_failed", "Failed to fetch report", err))
+		return
+	}
+	cfg.respondWithJSON(w, http.StatusOK, reportFromDB(resolved))
+}
+
+// Withholds {chirpID} from everyone until it is restored
+func (cfg *apiConfig) hideChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, true)
+}
+
+func (cfg *apiConfig) restoreChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, false)
+}
+
+func (cfg *apiConfig) setChirpHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	var updated int64
+	action := auditChirpRestore
+	if hidden {
+		updated, err = qtx.HideChirp(r.Context(), chirpID)
+		action = auditChirpHide
+	} else {
+		updated, err = qtx.UnhideChirp(r.Context(), chirpID)
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	if updated == 0 {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	if err := recordAudit(r, qtx, action, "chirp", chirpID.String(), nil); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Brings back {chirpID} after its author deleted it. Deleting only sets
+// deleted_at, so the chirp returns with its replies, likes and entities.
+func (cfg *apiConfig) undeleteChirpHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	restored, err := qtx.RestoreChirp(r.Context(), chirpID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	if restored == 0 {
+		cfg.respondWithError(w, r, apierror.NotFound("deleted_chirp_not_found", "No deleted chirp with this ID"))
+		return
+	}
+	if err := recordAudit(r, qtx, auditChirpUndelete, "chirp", chirpID.String(), nil); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirp_update_failed", "Failed to update chirp", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Suspends {userID}: they can no longer log in or use the API and their
+// chirps are withheld from every listing. Only users ranked below the
+// caller can be suspended.
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_suspend_failed", "Failed to suspend user", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	if err := ensureOutranks(r, qtx, userID); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	suspended, err := suspendUser(r.Context(), qtx, userID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_suspend_failed", "Failed to suspend user", err))
+		return
+	}
+	if suspended == 0 {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	if err := recordAudit(r, qtx, auditUserSuspend, "user", userID.String(), nil); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_suspend_failed", "Failed to suspend user", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_suspend_failed", "Failed to suspend user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Lifts the suspension of {userID}. Like suspending, only users ranked
+// below the caller can be unsuspended.
+func (cfg *apiConfig) unsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_unsuspend_failed", "Failed to unsuspend user", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	if err := ensureOutranks(r, qtx, userID); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	updated, err := qtx.UnsuspendUser(r.Context(), userID)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_unsuspend_failed", "Failed to unsuspend user", err))
+		return
+	}
+	if updated == 0 {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	if err := recordAudit(r, qtx, auditUserUnsuspend, "user", userID.String(), nil); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_unsuspend_failed", "Failed to unsuspend user", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_unsuspend_failed", "Failed to unsuspend user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Moderators may only act against users ranked below them, so no
+// moderator can suspend or unsuspend an admin or another moderator
+func ensureOutranks(r *http.Request, q *database.Queries, targetID uuid.UUID) error {
+	target, err := q.GetUserByID(r.Context(), targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errUserNotFound
+	}
+	if err != nil {
+		return apierror.Internal("user_fetch_failed", "Failed to fetch user", err)
+	}
+	role, _ := roleFromContext(r.Context())
+	if !role.Outranks(auth.Role(target.Role)) {
+		return errTargetOutranks
+	}
+	return nil
+}
+
+// Marks a user suspended and signs them out of every session
+func suspendUser(ctx context.Context, q *database.Queries, userID uuid.UUID) (int64, error) {
+	suspended, err := q.SuspendUser(ctx, userID)
+	if err != nil || suspended == 0 {
+		return suspended, err
+	}
+	return suspended, q.RevokeAllRefreshTokensForUser(ctx, userID)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chirpy.com/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestSuspensionRequiresOutrankingTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     auth.Role
+		wantStatus int
+	}{
+		{"moderator targets user", auth.RoleUser, http.StatusNoContent},
+		{"moderator targets moderator", auth.RoleModerator, http.StatusForbidden},
+		{"moderator targets admin", auth.RoleAdmin, http.StatusForbidden},
+	}
+	for _, action := range []string{"suspend", "unsuspend"} {
+		for _, tt := range tests {
+			t.Run(action+"/"+tt.name, func(t *testing.T) {
+				cfg, fake := newTestConfig(t)
+				moderator := fake.addUser(auth.RoleModerator, false)
+				target := fake.addUser(tt.target, action == "unsuspend")
+				handler, query := cfg.suspendUserHandler, "SuspendUser"
+				if action == "unsuspend" {
+					handler, query = cfg.unsuspendUserHandler, "UnsuspendUser"
+				}
+
+				req := httptest.NewRequest(http.MethodPost, "/admin/users/"+target.ID.String()+"/"+action, nil)
+				req.SetPathValue("userID", target.ID.String())
+				req.Header.Set("Authorization", bearer(t, cfg, moderator))
+				rec := httptest.NewRecorder()
+				cfg.middlewareRole(auth.RoleModerator, handler)(rec, req)
+
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+				}
+				allowed := tt.wantStatus == http.StatusNoContent
+				if fake.didRun(query) != allowed {
+					t.Errorf("%s ran = %v, want %v", query, !allowed, allowed)
+				}
+				if fake.didRun("CreateAuditEntry") != allowed {
+					t.Errorf("CreateAuditEntry ran = %v, want %v", !allowed, allowed)
+				}
+				if !allowed && !strings.Contains(rec.Body.String(), `"target_outranks_caller"`) {
+					t.Errorf("body = %s, want code target_outranks_caller", rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestSetChirpHiddenFailsWhenAuditFails(t *testing.T) {
+	for _, hidden := range []bool{true, false} {
+		cfg, fake := newTestConfig(t)
+		fake.failing = "CreateAuditEntry"
+		moderator := fake.addUser(auth.RoleModerator, false)
+		chirpID := uuid.New()
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/chirps/"+chirpID.String()+"/hide", nil)
+		req.SetPathValue("chirpID", chirpID.String())
+		req.Header.Set("Authorization", bearer(t, cfg, moderator))
+		rec := httptest.NewRecorder()
+		handler := cfg.restoreChirpHandler
+		if hidden {
+			handler = cfg.hideChirpHandler
+		}
+		cfg.middlewareRole(auth.RoleModerator, handler)(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("hidden = %v: status = %d, want %d", hidden, rec.Code, http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestUndeleteChirpRequiresModerator(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       auth.Role
+		wantStatus int
+	}{
+		{"user", auth.RoleUser, http.StatusForbidden},
+		{"moderator", auth.RoleModerator, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, fake := newTestConfig(t)
+			caller := fake.addUser(tt.role, false)
+			chirpID := uuid.New()
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/chirps/"+chirpID.String()+"/undelete", nil)
+			req.SetPathValue("chirpID", chirpID.String())
+			req.Header.Set("Authorization", bearer(t, cfg, caller))
+			rec := httptest.NewRecorder()
+			cfg.middlewareRole(auth.RoleModerator, cfg.undeleteChirpHandler)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			restored := tt.wantStatus == http.StatusNoContent
+			if fake.didRun("RestoreChirp") != restored || fake.didRun("CreateAuditEntry") != restored {
+				t.Errorf("RestoreChirp and CreateAuditEntry ran = %v, %v, want %v",
+					fake.didRun("RestoreChirp"), fake.didRun("CreateAuditEntry"), restored)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Longest request ID accepted from a client before a new one is assigned
+const maxRequestIDLength = 128
+
+const requestInfoContextKey contextKey = "requestInfo"
+
+// Filled in as a request passes through the middleware chain, so the
+// request log can report what the inner handlers learned about it
+type requestInfo struct {
+	id     string
+	userID uuid.NullUUID
+}
+
+// Assigns every request an ID, or keeps the one the client sent in
+// X-Request-ID, and echoes it back in the response. Each request is logged
+// once it has been served.
+func (cfg *apiConfig) middlewareRequestLog(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &requestInfo{id: r.Header.Get(requestIDHeader)}
+		if !validRequestID(info.id) {
+			info.id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, info.id)
+		ctx := context.WithValue(r.Context(), requestInfoContextKey, info)
+
+		start := time.Now()
+		rec := newResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		attrs := []slog.Attr{
+			slog.String("request_id", info.id),
+			slog.String("method", r.Method),
+			slog.String("route", routeLabel(mux, r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.Status()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", rec.bytes),
+		}
+		if info.userID.Valid {
+			attrs = append(attrs, slog.String("user_id", info.userID.UUID.String()))
+		}
+		slog.LogAttrs(ctx, slog.LevelInfo, "request", attrs...)
+	})
+}
+
+// Client supplied IDs end up in the logs, so only short IDs made of
+// characters that can't break a log line are kept
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoContextKey).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// Lets the request log report who made the request once they have been
+// authenticated
+func setRequestUser(ctx context.Context, userID uuid.UUID) {
+	if info, ok := ctx.Value(requestInfoContextKey).(*requestInfo); ok {
+		info.userID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Records the status code and body size a handler writes. Flushing (for
+// the SSE stream) and hijacking (for WebSockets) are passed through.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (rw *responseRecorder) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// The status sent to the client. A handler that never writes anything
+// gets an implicit 200.
+func (rw *responseRecorder) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// Lets http.ResponseController reach the underlying writer
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *responseRecorder) Flush() {
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// The WebSocket library asserts http.Hijacker directly rather than going
+// through http.ResponseController
+func (rw *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil && rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
@@ -4,10 +4,11 @@ type ValidResponse struct {
 	Valid bool `json:"valid"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
 type CleanedChirp struct {
 	CleanedBody string `json:"cleaned_body"`
 }
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+)
+
+func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		cfg.respondWithError(w, r, errMissingRefreshToken)
+		return
+	}
+	// Revoking an unknown or already revoked token is not an error
+	if _, err := cfg.queries.RevokeRefreshToken(r.Context(), token); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("refresh_token_revoke_failed", "Failed to revoke refresh token", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
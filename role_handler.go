@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// Changes the role of {userID}. Admins cannot change their own role so
+// there is always at least one admin left. The new role reaches the user's
+// access token on their next refresh; admin routes check it right away.
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	callerID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	params := parameters{}
+	if err := decodeJSONBody(r, &params); err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_role", "Invalid role", apierror.FieldError{
+			Field:   "role",
+			Code:    "invalid",
+			Message: "must be one of user, moderator or admin",
+		}))
+		return
+	}
+	if userID == callerID {
+		cfg.respondWithError(w, r, apierror.Validation("cannot_change_own_role", "You cannot change your own role"))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	updated, err := qtx.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: string(role),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+	if updated == 0 {
+		cfg.respondWithError(w, r, errUserNotFound)
+		return
+	}
+	err = recordAudit(r, qtx, auditUserSetRole, "user", userID.String(), map[string]any{"role": role})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
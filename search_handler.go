@@ -0,0 +1,283 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+// A chirp matching a search along with how well it matched. The snippet
+// is the HTML-escaped body with matching terms wrapped in <mark> tags, so
+// the tags are the only markup it contains.
+type SearchResult struct {
+	Chirp
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type UserSearchResult struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle,omitempty"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// Searches chirp bodies. ?q= uses web search syntax: "quoted phrases",
+// OR and -excluded words. Results can be narrowed with ?author_id=,
+// ?since= and ?until= and are ordered by ?sort=relevance (the default) or
+// ?sort=recent. Paginated with ?limit= and ?cursor= like GET /api/chirps.
+func (cfg *apiConfig) searchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		cfg.respondWithError(w, r, invalidQueryParam("q", "must not be empty"))
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if raw := query.Get("author_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			cfg.respondWithError(w, r, invalidQueryParam("author_id", "must be a valid UUID"))
+			return
+		}
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	since, err := parseTimeParam(query, "since")
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	until, err := parseTimeParam(query, "until")
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+
+	sortOrder := query.Get("sort")
+	if sortOrder == "" {
+		sortOrder = "relevance"
+	}
+	if sortOrder != "relevance" && sortOrder != "recent" {
+		cfg.respondWithError(w, r, invalidQueryParam("sort", "must be relevance or recent"))
+		return
+	}
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	var results []SearchResult
+	var nextCursor string
+	if sortOrder == "relevance" {
+		cursorRank, cursorID, err := parseRankCursorParam(query)
+		if err != nil {
+			cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+			return
+		}
+		rows, err := cfg.queries.SearchChirpsByRank(r.Context(), database.SearchChirpsByRankParams{
+			Query:      q,
+			AuthorID:   authorID,
+			Since:      since,
+			Until:      until,
+			CursorRank: cursorRank,
+			CursorID:   cursorID,
+			ViewerID:   viewerFromContext(r.Context()),
+			Limit:      int32(limit + 1),
+		})
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("search_failed", "Failed to search chirps", err))
+			return
+		}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			last := rows[len(rows)-1]
+			nextCursor = encodeRankCursor(last.Rank, last.ID)
+		}
+		for _, row := range rows {
+			results = append(results, searchResult(database.Chirp{
+				ID:           row.ID,
+				CreatedAt:    row.CreatedAt,
+				UpdatedAt:    row.UpdatedAt,
+				Body:         row.Body,
+				UserID:       row.UserID,
+				DeletedAt:    row.DeletedAt,
+				InReplyToID:  row.InReplyToID,
+				ThreadRootID: row.ThreadRootID,
+				RechirpOfID:  row.RechirpOfID,
+				QuoteOfID:    row.QuoteOfID,
+			}, row.Rank, row.Snippet))
+		}
+	} else {
+		cursorCreatedAt, cursorID, err := parseCursorParam(query)
+		if err != nil {
+			cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+			return
+		}
+		rows, err := cfg.queries.SearchChirpsByDate(r.Context(), database.SearchChirpsByDateParams{
+			Query:           q,
+			AuthorID:        authorID,
+			Since:           since,
+			Until:           until,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			ViewerID:        viewerFromContext(r.Context()),
+			Limit:           int32(limit + 1),
+		})
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("search_failed", "Failed to search chirps", err))
+			return
+		}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			last := rows[len(rows)-1]
+			nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		}
+		for _, row := range rows {
+			results = append(results, searchResult(database.Chirp{
+				ID:           row.ID,
+				CreatedAt:    row.CreatedAt,
+				UpdatedAt:    row.UpdatedAt,
+				Body:         row.Body,
+				UserID:       row.UserID,
+				DeletedAt:    row.DeletedAt,
+				InReplyToID:  row.InReplyToID,
+				ThreadRootID: row.ThreadRootID,
+				RechirpOfID:  row.RechirpOfID,
+				QuoteOfID:    row.QuoteOfID,
+			}, row.Rank, row.Snippet))
+		}
+	}
+	if nextCursor != "" {
+		w.Header().Set("Link", nextPageLink(r.URL, nextCursor))
+	}
+
+	chirps := make([]*Chirp, len(results))
+	for i := range results {
+		chirps[i] = &results[i].Chirp
+	}
+	if err := cfg.hydrateChirps(r.Context(), chirps); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+	cfg.respondWithJSON(w, http.StatusOK, results)
+}
+
+// Finds users whose handle starts with ?q=, ignoring case. Emails are
+// never matched so the search can't be used to probe which addresses are
+// registered. Paginated with ?limit= and ?cursor=.
+func (cfg *apiConfig) searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(query.Get("q")), "@"))
+	if prefix == "" {
+		cfg.respondWithError(w, r, invalidQueryParam("q", "must not be empty"))
+		return
+	}
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	rows, err := cfg.queries.SearchUsers(r.Context(), database.SearchUsersParams{
+		Prefix:          escapeLike(prefix),
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("search_failed", "Failed to search users", err))
+		return
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+
+	users := make([]UserSearchResult, len(rows))
+	for i, row := range rows {
+		users[i] = UserSearchResult{
+			ID:          row.ID,
+			Handle:      row.Handle.String,
+			IsChirpyRed: row.IsChirpyRed,
+		}
+	}
+	cfg.respondWithJSON(w, http.StatusOK, users)
+}
+
+func searchResult(dbChirp database.Chirp, rank float32, snippet string) SearchResult {
+	return SearchResult{
+		Chirp:   chirpFromDB(dbChirp),
+		Rank:    rank,
+		Snippet: snippet,
+	}
+}
+
+// Reads an optional RFC 3339 timestamp or YYYY-MM-DD date from the query
+func parseTimeParam(query url.Values, name string) (sql.NullTime, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return sql.NullTime{Time: t, Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, invalidQueryParam(name, "must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// Relevance-ordered results page on (rank, id) instead of (created_at, id)
+func encodeRankCursor(rank float32, id uuid.UUID) string {
+	raw := strconv.FormatFloat(float64(rank), 'g', -1, 32) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseRankCursorParam(query url.Values) (sql.NullFloat64, uuid.NullUUID, error) {
+	raw := query.Get("cursor")
+	if raw == "" {
+		return sql.NullFloat64{}, uuid.NullUUID{}, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.NullUUID{}, errors.New("invalid cursor")
+	}
+	rawRank, rawID, found := strings.Cut(string(decoded), "|")
+	if !found {
+		return sql.NullFloat64{}, uuid.NullUUID{}, errors.New("invalid cursor")
+	}
+	rank, err := strconv.ParseFloat(rawRank, 32)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.NullUUID{}, errors.New("invalid cursor")
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.NullUUID{}, errors.New("invalid cursor")
+	}
+	return sql.NullFloat64{Float64: rank, Valid: true}, uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+// Escapes the LIKE wildcards so user input only ever matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
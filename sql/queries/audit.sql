@@ -0,0 +1,14 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (id, created_at, actor_id, action, target_type, target_id, details)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5);
+
+-- name: ListAuditEntries :many
+SELECT *
+FROM audit_log
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,59 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (muter_id, muted_id) DO NOTHING;
+
+-- name: UnmuteUser :execrows
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+   OR (follower_id = $2 AND followee_id = $1);
+
+-- name: HasBlockBetween :one
+SELECT EXISTS (
+  SELECT 1
+  FROM blocks
+  WHERE (blocker_id = sqlc.arg('user_id') AND blocked_id = ANY(sqlc.arg('other_ids')::uuid[]))
+     OR (blocked_id = sqlc.arg('user_id') AND blocker_id = ANY(sqlc.arg('other_ids')::uuid[]))
+);
+
+-- name: GetHiddenAuthorIDs :many
+SELECT author_id
+FROM hidden_authors
+WHERE viewer_id = $1;
+
+-- name: ListBlocks :many
+SELECT users.id, users.handle, blocks.created_at
+FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (blocks.created_at, blocks.blocked_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY blocks.created_at DESC, blocks.blocked_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListMutes :many
+SELECT users.id, users.handle, mutes.created_at
+FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (mutes.created_at, mutes.muted_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY mutes.created_at DESC, mutes.muted_id DESC
+LIMIT sqlc.arg('limit');
@@ -8,19 +8,167 @@ CREATE TABLE chirps (
 );
 
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
--- name: GetAllChirps :many
+-- name: ListChirpsAsc :many
 SELECT *
 FROM chirps
-ORDER BY created_at ASC;
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT *
+FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTimeline :many
+SELECT chirps.*
+FROM chirps
+WHERE chirps.user_id IN (
+    SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('follower_id')
+  )
+  AND chirps.deleted_at IS NULL
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.arg('follower_id') AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirp :one
 SELECT *
 FROM chirps
-WHERE ID = $1;
+WHERE chirps.id = sqlc.arg('id') AND deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  );
+
+-- name: GetChirpAuthorID :one
+SELECT user_id
+FROM chirps
+WHERE id = $1;
+
+-- name: GetThread :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.*, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('root_id')
+  UNION ALL
+    SELECT chirps.*, thread.depth + 1
+    FROM chirps
+    JOIN thread ON chirps.in_reply_to_id = thread.id
+    WHERE chirps.thread_root_id = sqlc.arg('root_id')
+)
+SELECT id, created_at, updated_at, body, user_id, deleted_at, in_reply_to_id, thread_root_id, rechirp_of_id, quote_of_id, depth::int AS depth,
+  (
+    thread.hidden_at IS NOT NULL
+    OR EXISTS (SELECT 1 FROM users WHERE users.id = thread.user_id AND users.suspended_at IS NOT NULL)
+    OR EXISTS (
+      SELECT 1 FROM hidden_authors
+      WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = thread.user_id
+    )
+  )::bool AS withheld
+FROM thread
+ORDER BY depth ASC, created_at ASC, id ASC;
+
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  );
+
+-- name: GetRechirpCounts :many
+SELECT
+  target_id::uuid AS chirp_id,
+  COUNT(*) FILTER (WHERE kind = 'rechirp')::int AS rechirp_count,
+  COUNT(*) FILTER (WHERE kind = 'quote')::int AS quote_count
+FROM (
+    SELECT rechirp_of_id AS target_id, 'rechirp' AS kind
+    FROM chirps
+    WHERE rechirp_of_id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL
+  UNION ALL
+    SELECT quote_of_id AS target_id, 'quote' AS kind
+    FROM chirps
+    WHERE quote_of_id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL
+) AS reposts
+GROUP BY target_id;
+
+-- name: GetRechirpByUser :one
+SELECT *
+FROM chirps
+WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL;
+
+-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: RestoreChirp :execrows
+UPDATE chirps
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: UnhideChirp :execrows
+UPDATE chirps
+SET hidden_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- down.sql
 DROP TABLE chirps;
\ No newline at end of file
@@ -0,0 +1,54 @@
+-- name: UpsertHashtags :many
+INSERT INTO hashtags (id, name, created_at)
+SELECT gen_random_uuid(), name, NOW()
+FROM unnest(sqlc.arg('names')::text[]) AS name
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: CreateChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, start_offset, end_offset)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreateMention :exec
+INSERT INTO mentions (chirp_id, user_id, start_offset, end_offset)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetHashtagEntities :many
+SELECT chirp_hashtags.chirp_id, hashtags.name, chirp_hashtags.start_offset, chirp_hashtags.end_offset
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE chirp_hashtags.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_hashtags.chirp_id, chirp_hashtags.start_offset;
+
+-- name: GetMentionEntities :many
+SELECT mentions.chirp_id, mentions.user_id, users.handle, mentions.start_offset, mentions.end_offset
+FROM mentions
+JOIN users ON users.id = mentions.user_id
+WHERE mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY mentions.chirp_id, mentions.start_offset;
+
+-- name: ListChirpsByHashtag :many
+SELECT chirps.*
+FROM chirps
+WHERE chirps.id IN (
+    SELECT chirp_hashtags.chirp_id
+    FROM chirp_hashtags
+    JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+    WHERE hashtags.name = sqlc.arg('tag')
+  )
+  AND chirps.deleted_at IS NULL
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
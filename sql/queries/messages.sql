@@ -0,0 +1,81 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, direct_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: GetConversationByDirectKey :one
+SELECT *
+FROM conversations
+WHERE direct_key = $1;
+
+-- name: AddConversationMembers :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+SELECT sqlc.arg('conversation_id'), user_id, NOW()
+FROM unnest(sqlc.arg('user_ids')::uuid[]) AS user_id
+ON CONFLICT (conversation_id, user_id) DO NOTHING;
+
+-- name: GetConversationForMember :one
+SELECT conversations.*
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = sqlc.arg('conversation_id') AND conversation_members.user_id = sqlc.arg('user_id');
+
+-- name: ListConversations :many
+SELECT
+  conversations.id,
+  conversations.created_at,
+  conversations.updated_at,
+  conversations.direct_key,
+  (
+    SELECT COUNT(*)
+    FROM messages
+    WHERE messages.conversation_id = conversations.id
+      AND messages.sender_id <> conversation_members.user_id
+      AND (conversation_members.last_read_at IS NULL OR messages.created_at > conversation_members.last_read_at)
+  )::int AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_updated_at')::timestamp IS NULL
+    OR (conversations.updated_at, conversations.id) < (sqlc.narg('cursor_updated_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetConversationMembers :many
+SELECT conversation_id, user_id, last_read_at
+FROM conversation_members
+WHERE conversation_id = ANY(sqlc.arg('conversation_ids')::uuid[])
+ORDER BY conversation_id, joined_at, user_id;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = $2
+WHERE id = $1;
+
+-- name: ListMessages :many
+SELECT *
+FROM messages
+WHERE conversation_id = sqlc.arg('conversation_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetMessage :one
+SELECT *
+FROM messages
+WHERE id = $1 AND conversation_id = $2;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = GREATEST(last_read_at, COALESCE(sqlc.narg('read_at')::timestamp, NOW()))
+WHERE conversation_id = sqlc.arg('conversation_id') AND user_id = sqlc.arg('user_id');
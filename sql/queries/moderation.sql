@@ -0,0 +1,14 @@
+-- name: ListBannedWords :many
+SELECT * FROM banned_words
+ORDER BY word ASC;
+
+-- name: UpsertBannedWord :one
+INSERT INTO banned_words (word, action, created_at, updated_at)
+VALUES ($1, $2, NOW(), NOW())
+ON CONFLICT (word) DO UPDATE
+SET action = EXCLUDED.action, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteBannedWord :execrows
+DELETE FROM banned_words
+WHERE word = $1;
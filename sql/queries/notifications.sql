@@ -0,0 +1,41 @@
+-- name: CreateNotifications :many
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id)
+SELECT gen_random_uuid(), NOW(), recipient_id, sqlc.arg('actor_id'), sqlc.arg('kind'), sqlc.narg('chirp_id')
+FROM unnest(sqlc.arg('recipient_ids')::uuid[]) AS recipient_id
+WHERE NOT EXISTS (
+  SELECT 1 FROM hidden_authors
+  WHERE hidden_authors.viewer_id = recipient_id AND hidden_authors.author_id = sqlc.arg('actor_id')
+)
+ON CONFLICT (user_id, actor_id, kind, (COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid))) DO NOTHING
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT *
+FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotification :one
+SELECT *
+FROM notifications
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+  AND read_at IS NULL
+  AND (
+    sqlc.narg('up_to_created_at')::timestamp IS NULL
+    OR (created_at, id) <= (sqlc.narg('up_to_created_at')::timestamp, sqlc.narg('up_to_id')::uuid)
+  );
@@ -0,0 +1,30 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, chirp_id, reporter_id, reason, details)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: CreateFlagReport :exec
+INSERT INTO reports (id, created_at, chirp_id, reason, details)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3);
+
+-- name: GetReport :one
+SELECT *
+FROM reports
+WHERE id = $1;
+
+-- name: ListReports :many
+SELECT reports.*, chirps.body AS chirp_body, chirps.user_id AS chirp_user_id, chirps.hidden_at AS chirp_hidden_at
+FROM reports
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.status = sqlc.arg('status')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (reports.created_at, reports.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY reports.created_at ASC, reports.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ResolveReportsForChirp :execrows
+UPDATE reports
+SET status = 'resolved', decision = sqlc.arg('decision'), moderator_note = sqlc.arg('moderator_note'), resolved_at = NOW()
+WHERE chirp_id = sqlc.arg('chirp_id') AND status = 'open';
@@ -0,0 +1,73 @@
+-- name: SearchChirpsByRank :many
+SELECT chirps.*,
+  ts_rank_cd(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank,
+  ts_headline(
+    'english',
+    replace(replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+    websearch_to_tsquery('english', sqlc.arg('query')),
+    'StartSel=<mark>, StopSel=</mark>'
+  )::text AS snippet
+FROM chirps
+WHERE chirps.search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+  AND (
+    sqlc.narg('cursor_rank')::real IS NULL
+    OR (ts_rank_cd(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real, chirps.id)
+      < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY rank DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirpsByDate :many
+SELECT chirps.*,
+  ts_rank_cd(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank,
+  ts_headline(
+    'english',
+    replace(replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+    websearch_to_tsquery('english', sqlc.arg('query')),
+    'StartSel=<mark>, StopSel=</mark>'
+  )::text AS snippet
+FROM chirps
+WHERE chirps.search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM hidden_authors
+    WHERE hidden_authors.viewer_id = sqlc.narg('viewer_id')::uuid AND hidden_authors.author_id = chirps.user_id
+  )
+  AND chirps.hidden_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = chirps.user_id AND users.suspended_at IS NOT NULL
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchUsers :many
+SELECT id, created_at, handle, is_chirpy_red
+FROM users
+WHERE LOWER(handle) LIKE sqlc.arg('prefix')::text || '%'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -15,3 +16,51 @@ WHERE email = $1;
 
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: UpdateUser :one
+UPDATE users
+SET email = $2, hashed_password = $3, handle = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpgradeUserToChirpyRed :execrows
+UPDATE users
+SET is_chirpy_red = true, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPasswordHash :exec
+UPDATE users
+SET hashed_password = $2
+WHERE id = $1;
+
+-- name: GetUsersByHandles :many
+SELECT id, handle FROM users
+WHERE LOWER(handle) = ANY(sqlc.arg('handles')::text[]);
+
+-- name: GetUserIDs :many
+SELECT id FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnsuspendUser :execrows
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1;
@@ -0,0 +1,4 @@
+-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events (id, received_at, event)
+VALUES ($1, NOW(), $2)
+ON CONFLICT (id) DO NOTHING;
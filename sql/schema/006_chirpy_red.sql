@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE webhook_events (
+  id TEXT PRIMARY KEY,
+  received_at TIMESTAMP NOT NULL,
+  event TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_events;
+ALTER TABLE users DROP COLUMN is_chirpy_red;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE banned_words (
+  word TEXT PRIMARY KEY,
+  action TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_flags (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE banned_words;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN in_reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+-- No foreign key: a hard-deleted root must not take other users' replies with it
+ALTER TABLE chirps ADD COLUMN thread_root_id UUID;
+UPDATE chirps SET thread_root_id = id;
+ALTER TABLE chirps ALTER COLUMN thread_root_id SET NOT NULL;
+
+CREATE INDEX chirps_in_reply_to_id_idx ON chirps (in_reply_to_id);
+CREATE INDEX chirps_thread_root_id_idx ON chirps (thread_root_id);
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN thread_root_id;
+ALTER TABLE chirps DROP COLUMN in_reply_to_id;
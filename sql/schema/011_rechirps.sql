@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN rechirp_of_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+ALTER TABLE chirps ADD COLUMN quote_of_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- A user can only have one live rechirp of a given chirp
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_key ON chirps (user_id, rechirp_of_id)
+  WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX chirps_rechirp_of_id_idx ON chirps (rechirp_of_id) WHERE rechirp_of_id IS NOT NULL;
+CREATE INDEX chirps_quote_of_id_idx ON chirps (quote_of_id) WHERE quote_of_id IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN quote_of_id;
+ALTER TABLE chirps DROP COLUMN rechirp_of_id;
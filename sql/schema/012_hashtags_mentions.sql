@@ -0,0 +1,38 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN handle TEXT;
+
+-- Handles are unique regardless of case
+CREATE UNIQUE INDEX users_handle_key ON users (LOWER(handle));
+
+CREATE TABLE hashtags (
+  id UUID PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_hashtags (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  hashtag_id UUID NOT NULL REFERENCES hashtags(id) ON DELETE CASCADE,
+  start_offset INTEGER NOT NULL,
+  end_offset INTEGER NOT NULL,
+  PRIMARY KEY (chirp_id, start_offset)
+);
+
+CREATE INDEX chirp_hashtags_hashtag_id_idx ON chirp_hashtags (hashtag_id, chirp_id);
+
+CREATE TABLE mentions (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  start_offset INTEGER NOT NULL,
+  end_offset INTEGER NOT NULL,
+  PRIMARY KEY (chirp_id, start_offset)
+);
+
+CREATE INDEX mentions_user_id_idx ON mentions (user_id);
+
+-- +goose Down
+DROP TABLE mentions;
+DROP TABLE chirp_hashtags;
+DROP TABLE hashtags;
+DROP INDEX users_handle_key;
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE chirps
+  ADD COLUMN search_vector TSVECTOR
+  GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_vector_idx ON chirps USING GIN (search_vector);
+
+-- Prefix matches on handle for user search
+CREATE INDEX users_handle_prefix_idx ON users (LOWER(handle) text_pattern_ops);
+
+-- +goose Down
+DROP INDEX users_handle_prefix_idx;
+DROP INDEX chirps_search_vector_idx;
+ALTER TABLE chirps DROP COLUMN search_vector;
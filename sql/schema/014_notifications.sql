@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE notifications (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+  read_at TIMESTAMP
+);
+
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at, id);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Liking, unliking and liking again notifies only once
+CREATE UNIQUE INDEX notifications_dedupe_idx ON notifications (
+  user_id, actor_id, kind, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid)
+);
+
+-- +goose Down
+DROP TABLE notifications;
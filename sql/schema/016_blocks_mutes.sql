@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE blocks (
+  blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (blocker_id, blocked_id),
+  CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+  muter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  muted_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (muter_id, muted_id),
+  CHECK (muter_id <> muted_id)
+);
+
+-- Authors whose chirps and notifications a viewer never sees: anyone they
+-- blocked or muted and anyone who blocked them. Every read path filters
+-- through this view so the rule lives in one place.
+CREATE VIEW hidden_authors AS
+  SELECT blocker_id AS viewer_id, blocked_id AS author_id FROM blocks
+  UNION
+  SELECT blocked_id, blocker_id FROM blocks
+  UNION
+  SELECT muter_id, muted_id FROM mutes;
+
+-- +goose Down
+DROP VIEW hidden_authors;
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+-- Set by a moderator. Unlike deleted_at the author can't undo it.
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE reports (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  details TEXT NOT NULL DEFAULT '',
+  -- open until a moderator records a decision
+  status TEXT NOT NULL DEFAULT 'open',
+  decision TEXT,
+  moderator_note TEXT NOT NULL DEFAULT '',
+  resolved_at TIMESTAMP,
+  UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+
+-- +goose Down
+DROP TABLE reports;
+ALTER TABLE chirps DROP COLUMN hidden_at;
+ALTER TABLE users DROP COLUMN suspended_at;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+  CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- One row per privileged action. actor_id is NULL for actions taken
+-- outside the API, such as bootstrapping the first admin.
+CREATE TABLE audit_log (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  action TEXT NOT NULL,
+  target_type TEXT NOT NULL,
+  target_id TEXT NOT NULL,
+  details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at, id);
+
+-- +goose Down
+DROP TABLE audit_log;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- Chirps flagged by the word filter join the report queue as reports
+-- without a reporter, so moderators work them alongside user reports
+ALTER TABLE reports ALTER COLUMN reporter_id DROP NOT NULL;
+
+INSERT INTO reports (id, created_at, chirp_id, reason, details)
+SELECT gen_random_uuid(), MIN(created_at), chirp_id, 'flagged', string_agg(reason, ', ' ORDER BY reason)
+FROM chirp_flags
+GROUP BY chirp_id;
+
+DROP TABLE chirp_flags;
+
+-- +goose Down
+CREATE TABLE chirp_flags (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL
+);
+
+INSERT INTO chirp_flags (id, created_at, chirp_id, reason)
+SELECT gen_random_uuid(), created_at, chirp_id, unnest(string_to_array(details, ', '))
+FROM reports
+WHERE reporter_id IS NULL;
+
+DELETE FROM reports WHERE reporter_id IS NULL;
+ALTER TABLE reports ALTER COLUMN reporter_id SET NOT NULL;
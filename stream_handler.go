@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/entities"
+	"chirpy.com/internal/stream"
+	"github.com/google/uuid"
+)
+
+const (
+	// How many recent chirps a reconnecting client can catch up on
+	chirpStreamReplaySize = 1000
+	// How many chirps a client may fall behind before it is disconnected
+	chirpStreamBufferSize   = 64
+	streamHeartbeatInterval = 15 * time.Second
+	// Notifications are only replayed to WebSocket clients resuming a
+	// subscription, the notifications API is the durable record
+	notificationStreamReplaySize = 100
+	notificationStreamBufferSize = 16
+)
+
+// Streams newly created chirps as Server-Sent Events. ?author_id= and
+// ?hashtag= narrow the stream; when both are given a chirp must match both.
+// Clients resume after a disconnect with the Last-Event-ID header (or
+// ?last_event_id= where headers can't be set) and receive the chirps they
+// missed if they are still in the replay buffer.
+func (cfg *apiConfig) chirpStreamHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var topics []string
+	if raw := query.Get("author_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			cfg.respondWithError(w, r, invalidQueryParam("author_id", "must be a valid UUID"))
+			return
+		}
+		topics = append(topics, authorTopic(id))
+	}
+	if raw := query.Get("hashtag"); raw != "" {
+		tag := entities.NormalizeTag(raw)
+		if !entities.ValidTag(tag) {
+			cfg.respondWithError(w, r, invalidQueryParam("hashtag", "must be a valid hashtag"))
+			return
+		}
+		topics = append(topics, hashtagTopic(tag))
+	}
+
+	rawLastID := r.Header.Get("Last-Event-ID")
+	if rawLastID == "" {
+		rawLastID = query.Get("last_event_id")
+	}
+	var lastEventID uint64
+	if rawLastID != "" {
+		id, err := strconv.ParseUint(rawLastID, 10, 64)
+		if err != nil {
+			cfg.respondWithError(w, r, invalidQueryParam("last_event_id", "must be a non-negative integer"))
+			return
+		}
+		lastEventID = id
+	}
+	// Signed-in clients don't see authors they blocked or muted
+	filter, err := cfg.visibleTo(r.Context(), viewerFromContext(r.Context()), stream.AllTopics(topics...))
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("stream_failed", "Failed to open chirp stream", err))
+		return
+	}
+
+	// The stream outlives any server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		log.Printf("Error clearing write deadline for chirp stream: %s", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sub, missed := cfg.chirpHub.Subscribe(filter, lastEventID)
+	defer sub.Close()
+
+	for _, event := range missed {
+		if err := writeSSE(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			// Dropped for falling behind or the server is shutting down,
+			// the client reconnects with Last-Event-ID
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSE(w io.Writer, event stream.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
+
+// Pushes a newly created chirp to live subscribers
+func (cfg *apiConfig) publishChirp(chirp Chirp) {
+	data, err := json.Marshal(chirp)
+	if err != nil {
+		log.Printf("Error encoding chirp %s for streaming: %s", chirp.ID, err)
+		return
+	}
+	topics := []string{authorTopic(chirp.UserID)}
+	for _, hashtag := range chirp.Entities.Hashtags {
+		topics = append(topics, hashtagTopic(hashtag.Tag))
+	}
+	cfg.chirpHub.Publish("chirp", data, topics...)
+}
+
+func authorTopic(userID uuid.UUID) string {
+	return "author:" + userID.String()
+}
+
+func hashtagTopic(tag string) string {
+	return "hashtag:" + tag
+}
+
+// Events addressed to a single user, such as their notifications
+func recipientTopic(userID uuid.UUID) string {
+	return "recipient:" + userID.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+)
+
+// Lists chirps from the accounts the caller follows, newest first. Paginated
+// with ?limit= and ?cursor= like GET /api/chirps.
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	limit, err := parseLimit(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("limit", err.Error()))
+		return
+	}
+	cursorCreatedAt, cursorID, err := parseCursorParam(query)
+	if err != nil {
+		cfg.respondWithError(w, r, invalidQueryParam("cursor", err.Error()))
+		return
+	}
+
+	dbChirps, err := cfg.queries.GetTimeline(r.Context(), database.GetTimelineParams{
+		FollowerID:      userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           int32(limit + 1),
+	})
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("timeline_fetch_failed", "Failed to fetch timeline", err))
+		return
+	}
+	if len(dbChirps) > limit {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		cursor := encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+		w.Header().Set("Link", nextPageLink(r.URL, cursor))
+	}
+	chirps := chirpsFromDB(dbChirps)
+	if err := cfg.hydrateChirps(r.Context(), chirpPointers(chirps)); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("chirps_hydrate_failed", "Failed to load chirp details", err))
+		return
+	}
+	cfg.respondWithJSON(w, http.StatusOK, chirps)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"chirpy.com/internal/entities"
+)
+
+// Updates the authenticated user's email, password and/or handle. Omitted fields
+// are left unchanged. Changing the password revokes every refresh token
+// the user holds so other sessions must log in again.
+func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Handle   string `json:"handle"`
+	}
+
+	userID, ok := userIDFromContext(r.Context())
+	if !ok {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(r, &params)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	if params.Handle != "" && !entities.ValidHandle(params.Handle) {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_user", "The handle must be valid", invalidHandleField))
+		return
+	}
+
+	dbUser, err := cfg.queries.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondWithError(w, r, errUnauthorized)
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_fetch_failed", "Failed to fetch user", err))
+		return
+	}
+
+	email := dbUser.Email
+	if params.Email != "" {
+		email = params.Email
+	}
+	handle := dbUser.Handle
+	if params.Handle != "" {
+		handle = sql.NullString{String: params.Handle, Valid: true}
+	}
+	hash := dbUser.HashedPassword
+	passwordChanged := params.Password != ""
+	if passwordChanged {
+		hash, err = auth.HashPassword(params.Password)
+		if err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("password_hash_failed", "Failed to hash password", err))
+			return
+		}
+	}
+
+	// The new password and the revoked sessions land together or not at all
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.queries.WithTx(tx)
+	dbUser, err = qtx.UpdateUser(r.Context(), database.UpdateUserParams{
+		ID:             userID,
+		Email:          email,
+		HashedPassword: hash,
+		Handle:         handle,
+	})
+	if isUniqueViolationOn(err, usersHandleKey) {
+		cfg.respondWithError(w, r, errHandleTaken)
+		return
+	}
+	if isUniqueViolation(err) {
+		cfg.respondWithError(w, r, errEmailTaken)
+		return
+	}
+	if err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+
+	if passwordChanged {
+		if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), userID); err != nil {
+			cfg.respondWithError(w, r, apierror.Internal("session_revoke_failed", "Failed to revoke sessions", err))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		cfg.respondWithError(w, r, apierror.Internal("user_update_failed", "Failed to update user", err))
+		return
+	}
+
+	user := User{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		IsChirpyRed: dbUser.IsChirpyRed,
+		Handle:      dbUser.Handle.String,
+	}
+	cfg.respondWithJSON(w, http.StatusOK, user)
+}
@@ -1,49 +1,84 @@
 package main
 
 import (
-	"encoding/json"
+	"database/sql"
 	"fmt"
 	"net/http"
 
+	"chirpy.com/internal/apierror"
 	"chirpy.com/internal/auth"
 	"chirpy.com/internal/database"
+	"chirpy.com/internal/entities"
 )
 
+// Unique index enforcing case-insensitive handles
+const usersHandleKey = "users_handle_key"
+
+var invalidHandleField = apierror.FieldError{
+	Field:   "handle",
+	Code:    "invalid",
+	Message: fmt.Sprintf("must be 1 to %d letters, digits or underscores", entities.MaxHandleLength),
+}
+
 func (cfg *apiConfig) userHandler(w http.ResponseWriter, r *http.Request) {
 	type Parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := Parameters{}
-	err := decoder.Decode(&params)
-	// Respond with Error if problems marshalling JSON
+	err := decodeJSONBody(r, &params)
+	// Respond with Error if problems unmarshalling JSON
 	if err != nil {
-		msg := fmt.Sprintf("Error marshalling JSON: %s", err)
-		cfg.respondWithError(w, 500, msg)
+		cfg.respondWithError(w, r, err)
+		return
+	}
+	var fieldErrs []apierror.FieldError
+	if params.Email == "" {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "email", Code: "required", Message: "must not be empty"})
+	}
+	if params.Password == "" {
+		fieldErrs = append(fieldErrs, apierror.FieldError{Field: "password", Code: "required", Message: "must not be empty"})
+	}
+	if params.Handle != "" && !entities.ValidHandle(params.Handle) {
+		fieldErrs = append(fieldErrs, invalidHandleField)
+	}
+	if len(fieldErrs) > 0 {
+		cfg.respondWithError(w, r, apierror.Validation("invalid_user", "Email and password are required and the handle must be valid", fieldErrs...))
 		return
 	}
 	hash, err := auth.HashPassword(params.Password)
 	if err != nil {
-		cfg.respondWithError(w, 500, "Failed to hash password")
+		cfg.respondWithError(w, r, apierror.Internal("password_hash_failed", "Failed to hash password", err))
 		return
 	}
 	dbUser, err := cfg.queries.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hash,
+		Handle:         sql.NullString{String: params.Handle, Valid: params.Handle != ""},
 	})
 
+	if isUniqueViolationOn(err, usersHandleKey) {
+		cfg.respondWithError(w, r, errHandleTaken)
+		return
+	}
+	if isUniqueViolation(err) {
+		cfg.respondWithError(w, r, errEmailTaken)
+		return
+	}
 	if err != nil {
-		cfg.respondWithError(w, 500, "Failed to create user")
+		cfg.respondWithError(w, r, apierror.Internal("user_create_failed", "Failed to create user", err))
 		return
 	}
 
 	user := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		IsChirpyRed: dbUser.IsChirpyRed,
+		Handle:      dbUser.Handle.String,
 	}
 	cfg.respondWithJSON(w, 201, user)
 	return
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/database"
+	"chirpy.com/internal/stream"
+	"github.com/google/uuid"
+)
+
+// Blocks and mutes are enforced here and in the hidden_authors view rather
+// than by each handler. Listing queries take the viewer and exclude hidden
+// authors themselves, notifications from hidden actors are never created,
+// and live streams wrap their filters with visibleTo.
+
+// The caller, if the request is authenticated
+func viewerFromContext(ctx context.Context) uuid.NullUUID {
+	if userID, ok := userIDFromContext(ctx); ok {
+		return uuid.NullUUID{UUID: userID, Valid: true}
+	}
+	return uuid.NullUUID{}
+}
+
+// Reports errBlocked when userID has blocked, or been blocked by, any of
+// others. Interactions such as following, replying, liking and messaging
+// check this first.
+func (cfg *apiConfig) ensureNotBlocked(ctx context.Context, userID uuid.UUID, others ...uuid.UUID) error {
+	if len(others) == 0 {
+		return nil
+	}
+	blocked, err := cfg.queries.HasBlockBetween(ctx, database.HasBlockBetweenParams{
+		UserID:   userID,
+		OtherIds: others,
+	})
+	if err != nil {
+		return apierror.Internal("block_check_failed", "Failed to check blocks", err)
+	}
+	if blocked {
+		return errBlocked
+	}
+	return nil
+}
+
+// Narrows a chirp stream filter to the authors viewer may see. Blocks and
+// mutes made after subscribing are picked up by subscribing again.
+func (cfg *apiConfig) visibleTo(ctx context.Context, viewer uuid.NullUUID, filter stream.Filter) (stream.Filter, error) {
+	if !viewer.Valid {
+		return filter, nil
+	}
+	hidden, err := cfg.queries.GetHiddenAuthorIDs(ctx, viewer.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hidden) == 0 {
+		return filter, nil
+	}
+	topics := make([]string, len(hidden))
+	for i, author := range hidden {
+		topics[i] = authorTopic(author)
+	}
+	return stream.All(filter, stream.NoTopic(topics...)), nil
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"chirpy.com/internal/apierror"
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/stream"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = wsPongWait * 9 / 10
+	wsMaxMessageSize = 4096
+	// Outbound messages queued per connection. Once it is full events back
+	// up into the hub, which drops the connection as a slow consumer.
+	wsSendBufferSize = 64
+	// Application close codes, see RFC 6455 section 7.4.2
+	wsCloseTokenExpired = 4001
+	wsCloseAccountGone  = 4003
+	wsCloseSlowConsumer = 4008
+)
+
+// Channels a client can subscribe to
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelUser          = "user"
+	wsChannelNotifications = "notifications"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// Sent by clients. Types are "subscribe", "unsubscribe" and "auth"; the
+// latter swaps in a fresh access token before the current one expires.
+type wsClientMessage struct {
+	Type    string     `json:"type"`
+	Channel string     `json:"channel,omitempty"`
+	UserID  *uuid.UUID `json:"user_id,omitempty"`
+	// Resume a subscription after this event, like SSE's Last-Event-ID
+	LastEventID uint64 `json:"last_event_id,omitempty"`
+	Token       string `json:"token,omitempty"`
+}
+
+// Sent to clients. Types are "subscribed", "unsubscribed", "authenticated",
+// "event" and "error".
+type wsServerMessage struct {
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	UserID    *uuid.UUID      `json:"user_id,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	ID        uint64          `json:"id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Code      string          `json:"code,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// Upgrades to a WebSocket carrying live events for the channels the client
+// subscribes to. The access token is read from the Authorization header or,
+// for browsers which cannot set headers on WebSocket requests, from
+// ?access_token=. The connection is closed when the token expires unless
+// the client sends a newer one first.
+func (cfg *apiConfig) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		cfg.respondWithError(w, r, errMissingAccessToken)
+		return
+	}
+	claims, _, err := cfg.authenticate(r.Context(), token)
+	if err != nil {
+		cfg.respondWithError(w, r, err)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	c := &wsConn{
+		ctx:    r.Context(),
+		cfg:    cfg,
+		conn:   conn,
+		userID: claims.UserID,
+		send:   make(chan wsServerMessage, wsSendBufferSize),
+		renew:  make(chan time.Time, 1),
+		done:   make(chan struct{}),
+		subs:   map[string]*stream.Subscription{},
+	}
+	cfg.liveConns.Add(1)
+	defer cfg.liveConns.Done()
+	go c.readLoop()
+	c.writeLoop(claims.ExpiresAt)
+}
+
+// One client connection. readLoop handles client messages, writeLoop is
+// the only writer, and each subscription forwards hub events into send.
+type wsConn struct {
+	ctx    context.Context
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	send   chan wsServerMessage
+	// New token expiry times from "auth" messages
+	renew chan time.Time
+	// Closed once the connection is finishing
+	done      chan struct{}
+	closeOnce sync.Once
+	// Close frame sent by writeLoop, set before done is closed
+	closeCode   int
+	closeReason string
+
+	mu   sync.Mutex
+	subs map[string]*stream.Subscription
+	// Set once the connection stops taking subscriptions
+	unsubscribed bool
+	forwarders   sync.WaitGroup
+}
+
+// Asks writeLoop to send a close frame and finish. Only the first call
+// has any effect.
+func (c *wsConn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// Queues a message, waiting while the send buffer is full
+func (c *wsConn) enqueue(msg wsServerMessage) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	}
+}
+
+func (c *wsConn) sendError(code, message string) {
+	c.enqueue(wsServerMessage{Type: "error", Code: code, Message: message})
+}
+
+func (c *wsConn) writeLoop(expiresAt time.Time) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+	defer c.conn.Close()
+	defer c.unsubscribeAll()
+	shutdown := c.cfg.shutdown
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.write(msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case newExpiry := <-c.renew:
+			expiry.Reset(time.Until(newExpiry))
+		case <-expiry.C:
+			c.write(wsServerMessage{Type: "error", Code: "token_expired", Message: "Access token expired"})
+			c.close(wsCloseTokenExpired, "token expired")
+		case <-shutdown:
+			// Stop new events, deliver what is already queued, then say goodbye
+			shutdown = nil
+			c.unsubscribeAll()
+			c.drain()
+			c.close(websocket.CloseGoingAway, "server shutting down")
+		case <-c.done:
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				deadline := time.Now().Add(wsWriteWait)
+				c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeReason), deadline)
+			}
+			return
+		}
+	}
+}
+
+func (c *wsConn) write(msg wsServerMessage) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(msg)
+}
+
+// Writes queued messages until every forwarder has handed over the
+// events it still held. Subscriptions must already be closed.
+func (c *wsConn) drain() {
+	forwarded := make(chan struct{})
+	go func() {
+		c.forwarders.Wait()
+		close(forwarded)
+	}()
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.write(msg); err != nil {
+				return
+			}
+		case <-forwarded:
+			for {
+				select {
+				case msg := <-c.send:
+					if err := c.write(msg); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *wsConn) readLoop() {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.close(websocket.CloseNormalClosure, "")
+			} else {
+				c.close(websocket.CloseAbnormalClosure, "")
+			}
+			return
+		}
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendError("malformed_message", "Messages must be JSON objects")
+			continue
+		}
+		switch msg.Type {
+		case "subscribe":
+			c.subscribe(msg)
+		case "unsubscribe":
+			c.unsubscribe(msg)
+		case "auth":
+			c.reauthenticate(msg.Token)
+		default:
+			c.sendError("unknown_message_type", "Unknown message type "+msg.Type)
+		}
+	}
+}
+
+// Accepts a fresh access token for the same user and pushes back the
+// connection's expiry. A valid token for an account that has since been
+// suspended or deleted closes the connection.
+func (c *wsConn) reauthenticate(token string) {
+	claims, _, err := c.cfg.authenticate(c.ctx, token)
+	if err == nil && claims.UserID != c.userID {
+		err = errInvalidAccessToken
+	}
+	if err != nil {
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) || apiErr.Kind == apierror.KindInternal {
+			log.Printf("Error reauthenticating user %s: %s", c.userID, err)
+			c.sendError("auth_failed", "Failed to check access token")
+			return
+		}
+		c.sendError(apiErr.Code, apiErr.Detail)
+		// The token itself is valid for this connection, so it is the
+		// account that can no longer be used
+		if claims.UserID == c.userID {
+			c.close(wsCloseAccountGone, "account unavailable")
+		}
+		return
+	}
+	select {
+	case <-c.renew:
+	default:
+	}
+	c.renew <- claims.ExpiresAt
+	c.enqueue(wsServerMessage{Type: "authenticated", ExpiresAt: &claims.ExpiresAt})
+}
+
+func (c *wsConn) subscribe(msg wsClientMessage) {
+	key, err := wsSubscriptionKey(msg)
+	if err != nil {
+		c.sendError("invalid_channel", err.Error())
+		return
+	}
+	c.mu.Lock()
+	_, exists := c.subs[key]
+	c.mu.Unlock()
+	if exists {
+		c.enqueue(wsServerMessage{Type: "subscribed", Channel: msg.Channel, UserID: msg.UserID})
+		return
+	}
+
+	var sub *stream.Subscription
+	var missed []stream.Event
+	viewer := uuid.NullUUID{UUID: c.userID, Valid: true}
+	switch msg.Channel {
+	case wsChannelTimeline:
+		// Follows made after subscribing are picked up by subscribing again
+		followees, err := c.cfg.queries.GetFolloweeIDs(c.ctx, c.userID)
+		if err != nil {
+			log.Printf("Error loading followees for user %s: %s", c.userID, err)
+			c.sendError("subscribe_failed", "Failed to subscribe")
+			return
+		}
+		topics := make([]string, len(followees))
+		for i, followee := range followees {
+			topics[i] = authorTopic(followee)
+		}
+		filter, err := c.cfg.visibleTo(c.ctx, viewer, stream.AnyTopic(topics...))
+		if err != nil {
+			log.Printf("Error loading hidden authors for user %s: %s", c.userID, err)
+			c.sendError("subscribe_failed", "Failed to subscribe")
+			return
+		}
+		sub, missed = c.cfg.chirpHub.Subscribe(filter, msg.LastEventID)
+	case wsChannelUser:
+		filter, err := c.cfg.visibleTo(c.ctx, viewer, stream.AnyTopic(authorTopic(*msg.UserID)))
+		if err != nil {
+			log.Printf("Error loading hidden authors for user %s: %s", c.userID, err)
+			c.sendError("subscribe_failed", "Failed to subscribe")
+			return
+		}
+		sub, missed = c.cfg.chirpHub.Subscribe(filter, msg.LastEventID)
+	case wsChannelNotifications:
+		sub, missed = c.cfg.notificationHub.Subscribe(stream.AnyTopic(recipientTopic(c.userID)), msg.LastEventID)
+	}
+
+	c.mu.Lock()
+	if c.unsubscribed {
+		c.mu.Unlock()
+		sub.Close()
+		return
+	}
+	c.subs[key] = sub
+	c.forwarders.Add(1)
+	c.mu.Unlock()
+	c.enqueue(wsServerMessage{Type: "subscribed", Channel: msg.Channel, UserID: msg.UserID})
+	for _, event := range missed {
+		c.enqueue(wsEventMessage(msg, event))
+	}
+	go c.forward(msg, sub)
+}
+
+// Copies events from a subscription to the client until it ends
+func (c *wsConn) forward(msg wsClientMessage, sub *stream.Subscription) {
+	defer c.forwarders.Done()
+	for event := range sub.Events() {
+		c.enqueue(wsEventMessage(msg, event))
+	}
+	if sub.Lagged() {
+		c.close(wsCloseSlowConsumer, "slow consumer")
+	}
+}
+
+func (c *wsConn) unsubscribe(msg wsClientMessage) {
+	key, err := wsSubscriptionKey(msg)
+	if err != nil {
+		c.sendError("invalid_channel", err.Error())
+		return
+	}
+	c.mu.Lock()
+	sub, ok := c.subs[key]
+	delete(c.subs, key)
+	c.mu.Unlock()
+	if ok {
+		sub.Close()
+	}
+	c.enqueue(wsServerMessage{Type: "unsubscribed", Channel: msg.Channel, UserID: msg.UserID})
+}
+
+func (c *wsConn) unsubscribeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unsubscribed = true
+	for key, sub := range c.subs {
+		sub.Close()
+		delete(c.subs, key)
+	}
+}
+
+// Identifies a subscription so the same channel isn't subscribed twice
+func wsSubscriptionKey(msg wsClientMessage) (string, error) {
+	switch msg.Channel {
+	case wsChannelTimeline, wsChannelNotifications:
+		return msg.Channel, nil
+	case wsChannelUser:
+		if msg.UserID == nil {
+			return "", errors.New("the user channel requires user_id")
+		}
+		return wsChannelUser + ":" + msg.UserID.String(), nil
+	default:
+		return "", errors.New("unknown channel " + msg.Channel)
+	}
+}
+
+func wsEventMessage(msg wsClientMessage, event stream.Event) wsServerMessage {
+	return wsServerMessage{
+		Type:    "event",
+		Channel: msg.Channel,
+		UserID:  msg.UserID,
+		Event:   event.Type,
+		ID:      event.ID,
+		Data:    event.Data,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy.com/internal/auth"
+	"chirpy.com/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestWebsocketHandlerRejectsUnavailableAccounts(t *testing.T) {
+	cfg, fake := newTestConfig(t)
+	suspended := fake.addUser(auth.RoleUser, true)
+	deleted := database.User{ID: uuid.New(), Role: string(auth.RoleUser)}
+
+	tests := []struct {
+		name       string
+		user       database.User
+		wantStatus int
+		wantCode   string
+	}{
+		{"suspended", suspended, http.StatusForbidden, "account_suspended"},
+		{"deleted", deleted, http.StatusUnauthorized, "invalid_access_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+			req.Header.Set("Authorization", bearer(t, cfg, tt.user))
+			rec := httptest.NewRecorder()
+			cfg.websocketHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), `"`+tt.wantCode+`"`) {
+				t.Errorf("body = %s, want code %s", rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestReauthenticate(t *testing.T) {
+	cfg, fake := newTestConfig(t)
+	active := fake.addUser(auth.RoleUser, false)
+	suspended := fake.addUser(auth.RoleUser, true)
+	deleted := database.User{ID: uuid.New(), Role: string(auth.RoleUser)}
+
+	tests := []struct {
+		name       string
+		conn       database.User
+		token      database.User
+		wantClosed bool
+		wantCode   string
+	}{
+		{"active", active, active, false, ""},
+		{"other user", active, suspended, false, "account_suspended"},
+		{"suspended", suspended, suspended, true, "account_suspended"},
+		{"deleted", deleted, deleted, true, "invalid_access_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &wsConn{
+				ctx:    context.Background(),
+				cfg:    cfg,
+				userID: tt.conn.ID,
+				send:   make(chan wsServerMessage, 1),
+				renew:  make(chan time.Time, 1),
+				done:   make(chan struct{}),
+			}
+			c.reauthenticate(strings.TrimPrefix(bearer(t, cfg, tt.token), "Bearer "))
+
+			msg := <-c.send
+			if tt.wantCode == "" {
+				if msg.Type != "authenticated" {
+					t.Errorf("message = %+v, want authenticated", msg)
+				}
+			} else if msg.Code != tt.wantCode {
+				t.Errorf("message = %+v, want code %s", msg, tt.wantCode)
+			}
+			select {
+			case <-c.done:
+				if !tt.wantClosed {
+					t.Errorf("connection closed with code %d", c.closeCode)
+				} else if c.closeCode != wsCloseAccountGone {
+					t.Errorf("close code = %d, want %d", c.closeCode, wsCloseAccountGone)
+				}
+			default:
+				if tt.wantClosed {
+					t.Error("connection left open")
+				}
+			}
+		})
+	}
+}